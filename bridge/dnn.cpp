@@ -0,0 +1,93 @@
+//go:build dnn
+// +build dnn
+
+#include "dnn.h"
+
+DnnNet DnnNet_Read(const char* framework, const char* model,
+    const char* config) {
+  std::string fw(framework);
+  cv::dnn::Net net;
+  try {
+    if (fw == "caffe") {
+      net = cv::dnn::readNetFromCaffe(config, model);
+    } else if (fw == "tensorflow") {
+      net = cv::dnn::readNetFromTensorflow(model, config);
+    } else if (fw == "onnx") {
+      net = cv::dnn::readNetFromONNX(model);
+    } else if (fw == "darknet") {
+      net = cv::dnn::readNetFromDarknet(config, model);
+    }
+  } catch (const cv::Exception&) {
+    return new cv::dnn::Net();
+  }
+  if (!net.empty()) {
+    net.setPreferableBackend(cv::dnn::DNN_BACKEND_OPENCV);
+    net.setPreferableTarget(cv::dnn::DNN_TARGET_CPU);
+  }
+  return new cv::dnn::Net(net);
+}
+
+void DnnNet_Delete(DnnNet net) {
+  delete net;
+}
+
+int DnnNet_Empty(DnnNet net) {
+  return net->empty();
+}
+
+static void setBlob(DnnNet net, MatVec3b img, const struct BlobParams& params) {
+  cv::Size size(params.size.width, params.size.height);
+  if (size.area() == 0) {
+    size = img->size();
+  }
+  cv::Scalar mean(params.mean[0], params.mean[1], params.mean[2]);
+  cv::Mat blob = cv::dnn::blobFromImage(*img, params.scale, size, mean,
+    params.swapRB != 0, params.crop != 0);
+  net->setInput(blob);
+}
+
+Mat DnnNet_Forward(DnnNet net, MatVec3b img, struct BlobParams params) {
+  try {
+    setBlob(net, img, params);
+    // outputs are cloned because the net reuses its buffers in the next pass
+    return new cv::Mat(net->forward().clone());
+  } catch (const cv::Exception&) {
+    return new cv::Mat();
+  }
+}
+
+struct Mats DnnNet_ForwardOutputs(DnnNet net, MatVec3b img,
+    struct BlobParams params) {
+  std::vector<cv::Mat> outs;
+  try {
+    setBlob(net, img, params);
+    net->forward(outs, net->getUnconnectedOutLayersNames());
+  } catch (const cv::Exception&) {
+    Mats ms = {NULL, 0};
+    return ms;
+  }
+  Mat* mats = new Mat[outs.size()];
+  for (size_t i = 0; i < outs.size(); ++i) {
+    mats[i] = new cv::Mat(outs[i].clone());
+  }
+  Mats ms = {mats, (int)outs.size()};
+  return ms;
+}
+
+struct IntVector NMSBoxes(struct Rects rects, struct FloatVector scores,
+    float scoreThreshold, float nmsThreshold) {
+  std::vector<cv::Rect> boxes;
+  for (int i = 0; i < rects.length; ++i) {
+    Rect r = rects.rects[i];
+    boxes.push_back(cv::Rect(r.x, r.y, r.width, r.height));
+  }
+  std::vector<float> ss(scores.val, scores.val + scores.length);
+  std::vector<int> indices;
+  cv::dnn::NMSBoxes(boxes, ss, scoreThreshold, nmsThreshold, indices);
+  int* val = new int[indices.size()];
+  for (size_t i = 0; i < indices.size(); ++i) {
+    val[i] = indices[i];
+  }
+  IntVector ret = {val, (int)indices.size()};
+  return ret;
+}
@@ -0,0 +1,171 @@
+//go:build dnn
+// +build dnn
+
+package bridge
+
+/*
+#include <stdlib.h>
+#include "util.h"
+#include "opencv_bridge.h"
+#include "dnn.h"
+*/
+import "C"
+import (
+	"reflect"
+	"unsafe"
+)
+
+// DnnNet is a bind of `cv::dnn::Net`.
+type DnnNet struct {
+	p C.DnnNet
+}
+
+// ReadDnnNet reads a network model. framework is "caffe", "tensorflow",
+// "onnx" or "darknet", model is the file path of trained weights and config is
+// the file path of network configuration (e.g. Caffe's prototxt). The network
+// uses OpenCV backend on CPU. Returned DnnNet is empty when the model cannot
+// be read.
+func ReadDnnNet(framework string, model string, config string) DnnNet {
+	cFramework := C.CString(framework)
+	defer C.free(unsafe.Pointer(cFramework))
+	cModel := C.CString(model)
+	defer C.free(unsafe.Pointer(cModel))
+	cConfig := C.CString(config)
+	defer C.free(unsafe.Pointer(cConfig))
+	return DnnNet{p: C.DnnNet_Read(cFramework, cModel, cConfig)}
+}
+
+// Delete DnnNet's pointer.
+func (n *DnnNet) Delete() {
+	C.DnnNet_Delete(n.p)
+	n.p = nil
+}
+
+// Empty returns the network has no layers or not.
+func (n *DnnNet) Empty() bool {
+	return C.DnnNet_Empty(n.p) != 0
+}
+
+// BlobParams is parameters of `cv::dnn::blobFromImage`.
+type BlobParams struct {
+	// Scale is a multiplier for image values.
+	Scale float64
+	// Size is spatial size for output image. Zero size means the same size
+	// as the input image.
+	Size Size
+	// Mean is subtracted from channels, the order is same as the image
+	// channels (BGR), or RGB when SwapRB is true.
+	Mean [3]float64
+	// SwapRB swaps the first and the last channels.
+	SwapRB bool
+	// Crop crops the image after resize with keeping aspect ratio.
+	Crop bool
+}
+
+// DefaultBlobParams returns default parameters of `cv::dnn::blobFromImage`.
+func DefaultBlobParams() BlobParams {
+	return BlobParams{
+		Scale: 1,
+	}
+}
+
+func (p *BlobParams) toC() C.struct_BlobParams {
+	cParams := C.struct_BlobParams{
+		scale: C.double(p.Scale),
+		size: C.struct_Size{
+			width:  C.int(p.Size.Width),
+			height: C.int(p.Size.Height),
+		},
+		swapRB: C.int(boolToInt(p.SwapRB)),
+		crop:   C.int(boolToInt(p.Crop)),
+	}
+	for i, m := range p.Mean {
+		cParams.mean[i] = C.double(m)
+	}
+	return cParams
+}
+
+// Forward runs forward pass with the image, and returns the output of the
+// network. Returns `false` when the forward pass fails.
+func (n *DnnNet) Forward(img MatVec3b, params BlobParams) (Tensor, bool) {
+	out := Mat{p: C.DnnNet_Forward(n.p, img.p, params.toC())}
+	defer out.Delete()
+	if out.Empty() {
+		return Tensor{}, false
+	}
+	return out.ToTensor()
+}
+
+// ForwardOutputs runs forward pass with the image, and returns outputs of all
+// unconnected output layers, e.g. YOLO networks have multiple output layers.
+// Returns `false` when the forward pass fails.
+func (n *DnnNet) ForwardOutputs(img MatVec3b, params BlobParams) ([]Tensor,
+	bool) {
+	ms := C.DnnNet_ForwardOutputs(n.p, img.p, params.toC())
+	defer C.Mats_Delete(ms)
+	if ms.mats == nil {
+		return nil, false
+	}
+	length := int(ms.length)
+	hdr := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(ms.mats)),
+		Len:  length,
+		Cap:  length,
+	}
+	cMats := *(*[]C.Mat)(unsafe.Pointer(&hdr))
+	tensors := make([]Tensor, length)
+	for i, p := range cMats {
+		// each Mat is deleted by Mats_Delete
+		out := Mat{p: p}
+		t, ok := out.ToTensor()
+		if !ok {
+			return nil, false
+		}
+		tensors[i] = t
+	}
+	return tensors, true
+}
+
+// NMSBoxes performs non maximum suppression with `cv::dnn::NMSBoxes`, and
+// returns indices of kept rectangles.
+func NMSBoxes(rects []Rect, scores []float32, scoreThreshold float32,
+	nmsThreshold float32) []int {
+	if len(rects) == 0 || len(rects) != len(scores) {
+		return []int{}
+	}
+	cRectArray := make([]C.struct_Rect, len(rects))
+	cScores := make([]C.float, len(scores))
+	for i, r := range rects {
+		cRectArray[i] = C.struct_Rect{
+			x:      C.int(r.X),
+			y:      C.int(r.Y),
+			width:  C.int(r.Width),
+			height: C.int(r.Height),
+		}
+		cScores[i] = C.float(scores[i])
+	}
+	cRects := C.struct_Rects{
+		rects:  (*C.Rect)(&cRectArray[0]),
+		length: C.int(len(rects)),
+	}
+	cScoreVec := C.struct_FloatVector{
+		val:    &cScores[0],
+		length: C.int(len(scores)),
+	}
+	ret := C.NMSBoxes(cRects, cScoreVec, C.float(scoreThreshold),
+		C.float(nmsThreshold))
+	defer C.IntVector_Delete(ret)
+
+	length := int(ret.length)
+	hdr := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(ret.val)),
+		Len:  length,
+		Cap:  length,
+	}
+	cIndices := *(*[]C.int)(unsafe.Pointer(&hdr))
+	indices := make([]int, length)
+	for i, idx := range cIndices {
+		indices[i] = int(idx)
+	}
+	return indices
+}
@@ -0,0 +1,43 @@
+#ifndef _DNN_H_
+#define _DNN_H_
+
+#include "opencv_bridge.h"
+
+#ifdef __cplusplus
+#include <opencv2/dnn.hpp>
+extern "C" {
+#endif
+
+typedef struct BlobParams {
+  double scale;
+  Size size;
+  double mean[3];
+  int swapRB;
+  int crop;
+} BlobParams;
+typedef struct FloatVector {
+  float* val;
+  int length;
+} FloatVector;
+
+#ifdef __cplusplus
+typedef cv::dnn::Net* DnnNet;
+#else
+typedef void* DnnNet;
+#endif
+
+DnnNet DnnNet_Read(const char* framework, const char* model,
+  const char* config);
+void DnnNet_Delete(DnnNet net);
+int DnnNet_Empty(DnnNet net);
+Mat DnnNet_Forward(DnnNet net, MatVec3b img, struct BlobParams params);
+struct Mats DnnNet_ForwardOutputs(DnnNet net, MatVec3b img,
+  struct BlobParams params);
+struct IntVector NMSBoxes(struct Rects rects, struct FloatVector scores,
+  float scoreThreshold, float nmsThreshold);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif //_DNN_H_
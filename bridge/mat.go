@@ -0,0 +1,288 @@
+package bridge
+
+/*
+#include <stdlib.h>
+#include "util.h"
+#include "opencv_bridge.h"
+*/
+import "C"
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Depths of Mat elements, the values are the same as OpenCV's.
+const (
+	// MatDepth8U is unsigned 8-bit integer.
+	MatDepth8U = 0
+	// MatDepth8S is signed 8-bit integer.
+	MatDepth8S = 1
+	// MatDepth16U is unsigned 16-bit integer.
+	MatDepth16U = 2
+	// MatDepth16S is signed 16-bit integer.
+	MatDepth16S = 3
+	// MatDepth32S is signed 32-bit integer.
+	MatDepth32S = 4
+	// MatDepth32F is 32-bit floating point.
+	MatDepth32F = 5
+	// MatDepth64F is 64-bit floating point.
+	MatDepth64F = 6
+)
+
+// MatType returns the type of Mat which has the depth and the number of
+// channels, same as `CV_MAKETYPE` macro, e.g. MatType(MatDepth8U, 3) is
+// `CV_8UC3`.
+func MatType(depth, channels int) int {
+	return depth + (channels-1)<<3
+}
+
+// depthSize returns the byte size of an element of the depth.
+func depthSize(depth int) int {
+	switch depth {
+	case MatDepth8U, MatDepth8S:
+		return 1
+	case MatDepth16U, MatDepth16S:
+		return 2
+	case MatDepth32S, MatDepth32F:
+		return 4
+	case MatDepth64F:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// Mat is a bind of `cv::Mat`, which can have any depth and any number of
+// channels (e.g. float depth maps, DNN outputs and optical flows).
+type Mat struct {
+	p C.Mat
+}
+
+// NewMat returns a new empty Mat.
+func NewMat() Mat {
+	return Mat{p: C.Mat_New()}
+}
+
+// NewMatFromBytes returns a new Mat of the type, and copies elements from the
+// bytes in row-major order. Returned Mat is empty when the length of bytes is
+// shorter than the size, and required to delete after using.
+func NewMatFromBytes(rows, cols, typ int, b []byte) Mat {
+	depth := typ & 7
+	channels := typ>>3 + 1
+	size := rows * cols * channels * depthSize(depth)
+	if rows <= 0 || cols <= 0 || size == 0 || len(b) < size {
+		return NewMat()
+	}
+	return Mat{p: C.Mat_NewFromBytes(C.int(rows), C.int(cols), C.int(typ),
+		toByteArray(b))}
+}
+
+// NewMatFromFloat32s returns a new Mat of 32-bit floating point which has the
+// channels, and copies elements from the values in row-major order. Returned
+// Mat is required to delete after using.
+func NewMatFromFloat32s(rows, cols, channels int, values []float32) Mat {
+	if len(values) == 0 {
+		return NewMat()
+	}
+	hdr := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(&values[0])),
+		Len:  len(values) * 4,
+		Cap:  len(values) * 4,
+	}
+	b := *(*[]byte)(unsafe.Pointer(&hdr))
+	return NewMatFromBytes(rows, cols, MatType(MatDepth32F, channels), b)
+}
+
+// Delete object.
+func (m *Mat) Delete() {
+	C.Mat_Delete(m.p)
+	m.p = nil
+}
+
+// Empty returns the Mat is empty or not.
+func (m *Mat) Empty() bool {
+	return C.Mat_Empty(m.p) != 0
+}
+
+// Rows returns the number of rows (= height) of the Mat. Returns -1 when the
+// Mat has more than 2 dimensions.
+func (m *Mat) Rows() int {
+	return int(C.Mat_Rows(m.p))
+}
+
+// Cols returns the number of columns (= width) of the Mat. Returns -1 when
+// the Mat has more than 2 dimensions.
+func (m *Mat) Cols() int {
+	return int(C.Mat_Cols(m.p))
+}
+
+// Type returns the type of the Mat, see MatType.
+func (m *Mat) Type() int {
+	return int(C.Mat_Type(m.p))
+}
+
+// Depth returns the depth of elements, e.g. MatDepth8U.
+func (m *Mat) Depth() int {
+	return int(C.Mat_Depth(m.p))
+}
+
+// Channels returns the number of channels.
+func (m *Mat) Channels() int {
+	return int(C.Mat_Channels(m.p))
+}
+
+// Shape returns the size of each dimension, e.g. [rows, cols] for 2D images
+// and [N, C, H, W] for DNN blobs.
+func (m *Mat) Shape() []int {
+	v := C.Mat_Shape(m.p)
+	defer C.IntVector_Delete(v)
+	length := int(v.length)
+	if length == 0 {
+		return []int{}
+	}
+	hdr := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(v.val)),
+		Len:  length,
+		Cap:  length,
+	}
+	cShape := *(*[]C.int)(unsafe.Pointer(&hdr))
+	shape := make([]int, length)
+	for i, s := range cShape {
+		shape[i] = int(s)
+	}
+	return shape
+}
+
+// ToBytes returns a copy of elements as bytes in row-major order, channels
+// are interleaved.
+func (m *Mat) ToBytes() []byte {
+	src := m
+	if C.Mat_IsContinuous(m.p) == 0 {
+		c := Mat{p: C.Mat_Clone(m.p)}
+		defer c.Delete()
+		src = &c
+	}
+	// elements are copied only once by toGoBytes without an intermediate copy
+	return toGoBytes(C.Mat_Data(src.p))
+}
+
+// ToFloat32s returns a copy of elements as float32 values in row-major order,
+// channels are interleaved. Returns `false` when the depth is not
+// MatDepth32F.
+func (m *Mat) ToFloat32s() ([]float32, bool) {
+	if m.Depth() != MatDepth32F {
+		return nil, false
+	}
+	b := m.ToBytes()
+	values := make([]float32, len(b)/4)
+	if len(values) == 0 {
+		return values, true
+	}
+	hdr := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(&values[0])),
+		Len:  len(values) * 4,
+		Cap:  len(values) * 4,
+	}
+	copy(*(*[]byte)(unsafe.Pointer(&hdr)), b)
+	return values, true
+}
+
+// Tensor is a multi-dimensional array of float values.
+type Tensor struct {
+	// Data is values of the tensor in row-major order.
+	Data []float32
+	// Shape is the size of each dimension.
+	Shape []int
+}
+
+// ToTensor returns a copy of the Mat as Tensor. Returns `false` when the
+// depth is not MatDepth32F.
+func (m *Mat) ToTensor() (Tensor, bool) {
+	values, ok := m.ToFloat32s()
+	if !ok {
+		return Tensor{}, false
+	}
+	shape := m.Shape()
+	if c := m.Channels(); c > 1 {
+		shape = append(shape, c)
+	}
+	return Tensor{
+		Data:  values,
+		Shape: shape,
+	}, true
+}
+
+// ConvertTo returns the Mat converted to the type with
+// `dst = src * alpha + beta`, e.g. MatType(MatDepth8U, 1) with alpha 255 to
+// convert a float mask in [0, 1] to a grayscale image. The number of channels
+// of the type is required to be the same as the Mat. Returned Mat is required
+// to delete after using.
+func (m *Mat) ConvertTo(typ int, alpha, beta float64) Mat {
+	return Mat{p: C.Mat_ConvertTo(m.p, C.int(typ), C.double(alpha),
+		C.double(beta))}
+}
+
+// Resize returns the Mat resized to the size with `cv::resize` in the same
+// type. Returned Mat is empty when the Mat cannot be resized (e.g. the size is
+// 0), and required to delete after using.
+func (m *Mat) Resize(width, height, interpolation int) Mat {
+	return Mat{p: C.Mat_Resize(m.p, C.int(width), C.int(height),
+		C.int(interpolation))}
+}
+
+// CopyMakeBorder returns the Mat surrounded by borders of each width, which
+// are filled with the value of each channel. Returned Mat is empty when a
+// width is negative, and required to delete after using.
+func (m *Mat) CopyMakeBorder(top, bottom, left, right int,
+	value [4]float64) Mat {
+	var cValue C.struct_Scalar
+	for i, v := range value {
+		cValue.val[i] = C.double(v)
+	}
+	return Mat{p: C.Mat_CopyMakeBorder(m.p, C.int(top), C.int(bottom),
+		C.int(left), C.int(right), cValue)}
+}
+
+// ToMatVec3b returns MatVec3b which shares elements with the Mat. Returned
+// MatVec3b is empty when the type is not `CV_8UC3`, and required to delete
+// after using.
+func (m *Mat) ToMatVec3b() MatVec3b {
+	return MatVec3b{p: C.Mat_ToMatVec3b(m.p)}
+}
+
+// ToMatVec4b returns MatVec4b which shares elements with the Mat. Returned
+// MatVec4b is empty when the type is not `CV_8UC4`, and required to delete
+// after using.
+func (m *Mat) ToMatVec4b() MatVec4b {
+	return MatVec4b{p: C.Mat_ToMatVec4b(m.p)}
+}
+
+// ToMatGray returns MatGray which shares elements with the Mat. Returned
+// MatGray is empty when the type is not `CV_8UC1`, and required to delete
+// after using.
+func (m *Mat) ToMatGray() MatGray {
+	return MatGray{p: C.Mat_ToMatGray(m.p)}
+}
+
+// ToMat returns Mat which shares elements with the MatVec3b. Returned Mat is
+// required to delete after using.
+func (m *MatVec3b) ToMat() Mat {
+	return Mat{p: C.MatVec3b_ToMat(m.p)}
+}
+
+// ToMat returns Mat which shares elements with the MatVec4b. Returned Mat is
+// required to delete after using.
+func (m *MatVec4b) ToMat() Mat {
+	return Mat{p: C.MatVec4b_ToMat(m.p)}
+}
+
+// ToMat returns Mat which shares elements with the MatGray. Returned Mat is
+// required to delete after using.
+func (m *MatGray) ToMat() Mat {
+	return Mat{p: C.MatGray_ToMat(m.p)}
+}
+
+// toRawData returns the size and a copy of elements of the Mat.
+func (m *Mat) toRawData() (int, int, []byte) {
+	return m.Cols(), m.Rows(), m.ToBytes()
+}
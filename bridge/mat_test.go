@@ -0,0 +1,89 @@
+package bridge
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestMat(t *testing.T) {
+	Convey("Given a 2x3 float Mat which has 2 channels", t, func() {
+		values := []float32{
+			0, 0.5, 1, 1.5, 2, 2.5,
+			3, 3.5, 4, 4.5, 5, 5.5,
+		}
+		m := NewMatFromFloat32s(2, 3, 2, values)
+		Reset(func() {
+			m.Delete()
+		})
+		Convey("Then accessors should return its properties", func() {
+			So(m.Empty(), ShouldBeFalse)
+			So(m.Rows(), ShouldEqual, 2)
+			So(m.Cols(), ShouldEqual, 3)
+			So(m.Depth(), ShouldEqual, MatDepth32F)
+			So(m.Channels(), ShouldEqual, 2)
+			So(m.Type(), ShouldEqual, MatType(MatDepth32F, 2))
+			So(m.Shape(), ShouldResemble, []int{2, 3})
+		})
+		Convey("When get float values", func() {
+			f, ok := m.ToFloat32s()
+			Convey("Then the values should be the same as the original", func() {
+				So(ok, ShouldBeTrue)
+				So(f, ShouldResemble, values)
+			})
+		})
+		Convey("When get it as tensor", func() {
+			tensor, ok := m.ToTensor()
+			Convey("Then channels should be the last dimension", func() {
+				So(ok, ShouldBeTrue)
+				So(tensor.Shape, ShouldResemble, []int{2, 3, 2})
+				So(tensor.Data, ShouldResemble, values)
+			})
+		})
+		Convey("When convert it to 8-bit", func() {
+			c := m.ConvertTo(MatType(MatDepth8U, 2), 2, 1)
+			defer c.Delete()
+			Convey("Then values should be scaled", func() {
+				So(c.ToBytes(), ShouldResemble, []byte{
+					1, 2, 3, 4, 5, 6,
+					7, 8, 9, 10, 11, 12,
+				})
+				_, ok := c.ToFloat32s()
+				So(ok, ShouldBeFalse)
+			})
+		})
+		Convey("When convert it to MatVec3b", func() {
+			v := m.ToMatVec3b()
+			defer v.Delete()
+			Convey("Then MatVec3b should be empty because of the type", func() {
+				So(v.Empty(), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given a MatVec3b", t, func() {
+		b := []byte{1, 2, 3, 4, 5, 6}
+		m := ToMatVec3b(2, 1, b)
+		Reset(func() {
+			m.Delete()
+		})
+		Convey("When get it as Mat", func() {
+			mat := m.ToMat()
+			defer mat.Delete()
+			Convey("Then Mat should have the same elements", func() {
+				So(mat.Type(), ShouldEqual, MatType(MatDepth8U, 3))
+				So(mat.ToBytes(), ShouldResemble, b)
+				v := mat.ToMatVec3b()
+				defer v.Delete()
+				So(v.Empty(), ShouldBeFalse)
+			})
+		})
+	})
+
+	Convey("Given bytes shorter than the size", t, func() {
+		m := NewMatFromBytes(2, 2, MatType(MatDepth8U, 1), []byte{1, 2, 3})
+		defer m.Delete()
+		Convey("Then Mat should be empty", func() {
+			So(m.Empty(), ShouldBeTrue)
+		})
+	})
+}
@@ -2,6 +2,136 @@
 
 #include <string.h>
 
+Mat Mat_New() {
+  return new cv::Mat();
+}
+
+Mat Mat_NewFromBytes(int rows, int cols, int type, struct ByteArray buf) {
+  cv::Mat* mat = new cv::Mat(rows, cols, type);
+  memcpy(mat->data, buf.data, mat->total() * mat->elemSize());
+  return mat;
+}
+
+void Mat_Delete(Mat m) {
+  delete m;
+}
+
+void Mats_Delete(struct Mats ms) {
+  for (int i = 0; i < ms.length; ++i) {
+    delete ms.mats[i];
+  }
+  delete[] ms.mats;
+}
+
+int Mat_Empty(Mat m) {
+  return m->empty();
+}
+
+int Mat_Rows(Mat m) {
+  return m->rows;
+}
+
+int Mat_Cols(Mat m) {
+  return m->cols;
+}
+
+int Mat_Type(Mat m) {
+  return m->type();
+}
+
+int Mat_Depth(Mat m) {
+  return m->depth();
+}
+
+int Mat_Channels(Mat m) {
+  return m->channels();
+}
+
+struct IntVector Mat_Shape(Mat m) {
+  int* val = new int[m->dims];
+  for (int i = 0; i < m->dims; ++i) {
+    val[i] = m->size[i];
+  }
+  IntVector v = {val, m->dims};
+  return v;
+}
+
+int Mat_IsContinuous(Mat m) {
+  return m->isContinuous();
+}
+
+Mat Mat_Clone(Mat m) {
+  return new cv::Mat(m->clone());
+}
+
+struct ByteArray Mat_Data(Mat m) {
+  ByteArray ret = {reinterpret_cast<char*>(m->data),
+    (int)(m->total() * m->elemSize())};
+  return ret;
+}
+
+Mat Mat_ConvertTo(Mat m, int type, double alpha, double beta) {
+  cv::Mat* dst = new cv::Mat();
+  m->convertTo(*dst, type, alpha, beta);
+  return dst;
+}
+
+Mat Mat_Resize(Mat m, int width, int height, int interpolation) {
+  cv::Mat* dst = new cv::Mat();
+  try {
+    cv::resize(*m, *dst, cv::Size(width, height), 0, 0, interpolation);
+  } catch (const cv::Exception&) {
+    dst->release();
+  }
+  return dst;
+}
+
+Mat Mat_CopyMakeBorder(Mat m, int top, int bottom, int left, int right,
+    struct Scalar value) {
+  cv::Mat* dst = new cv::Mat();
+  try {
+    cv::copyMakeBorder(*m, *dst, top, bottom, left, right,
+      cv::BORDER_CONSTANT,
+      cv::Scalar(value.val[0], value.val[1], value.val[2], value.val[3]));
+  } catch (const cv::Exception&) {
+    dst->release();
+  }
+  return dst;
+}
+
+MatVec3b Mat_ToMatVec3b(Mat m) {
+  if (m->type() != CV_8UC3) {
+    return new cv::Mat_<cv::Vec3b>();
+  }
+  return new cv::Mat_<cv::Vec3b>(*m);
+}
+
+MatVec4b Mat_ToMatVec4b(Mat m) {
+  if (m->type() != CV_8UC4) {
+    return new cv::Mat_<cv::Vec4b>();
+  }
+  return new cv::Mat_<cv::Vec4b>(*m);
+}
+
+MatGray Mat_ToMatGray(Mat m) {
+  if (m->type() != CV_8UC1) {
+    return new cv::Mat_<uchar>();
+  }
+  return new cv::Mat_<uchar>(*m);
+}
+
+Mat MatVec3b_ToMat(MatVec3b m) {
+  return new cv::Mat(*m);
+}
+
+Mat MatVec4b_ToMat(MatVec4b m) {
+  return new cv::Mat(*m);
+}
+
+Mat MatGray_ToMat(MatGray m) {
+  return new cv::Mat(*m);
+}
+
 MatVec3b MatVec3b_New() {
   return new cv::Mat_<cv::Vec3b>();
 }
@@ -27,48 +157,255 @@ int MatVec3b_Empty(MatVec3b m) {
   return m->empty();
 }
 
-struct RawData MatVec3b_ToRawData(MatVec3b m) {
-  int width = m->cols;
-  int height = m->rows;
-  int size = width * height * 3;
-  char* data = reinterpret_cast<char*>(m->data);
-  ByteArray byteData = {data, size};
-  RawData raw = {width, height, byteData};
-  return raw;
+int MatVec3b_Cols(MatVec3b m) {
+  return m->cols;
+}
+
+int MatVec3b_Rows(MatVec3b m) {
+  return m->rows;
 }
 
 MatVec3b RawData_ToMatVec3b(struct RawData r) {
-  int rows = r.height;
-  int cols = r.width;
-  cv::Mat_<cv::Vec3b>* mat = new cv::Mat_<cv::Vec3b>(rows, cols);
-  unsigned char* data = reinterpret_cast<unsigned char*>(r.data.data);
-  mat->data = data;
+  // r.data is Go memory which must not be referred after returning, so the
+  // Mat owns a copy of it.
+  cv::Mat_<cv::Vec3b>* mat = new cv::Mat_<cv::Vec3b>(r.height, r.width);
+  memcpy(mat->data, r.data.data, r.width * r.height * 3);
   return mat;
 }
 
+static struct ByteArray encode(const cv::Mat& m, const char* ext,
+    struct IntVector params) {
+  std::vector<int> param(params.val, params.val + params.length);
+  std::vector<uchar> data;
+  try {
+    if (!cv::imencode(ext, m, data, param)) {
+      data.clear();
+    }
+  } catch (const cv::Exception&) {
+    // the format is not supported by the linked OpenCV
+    data.clear();
+  }
+  if (data.empty()) {
+    ByteArray ret = {NULL, 0};
+    return ret;
+  }
+  return toByteArray(reinterpret_cast<const char*>(&data[0]), data.size());
+}
+
+struct ByteArray MatVec3b_Encode(MatVec3b m, const char* ext,
+    struct IntVector params) {
+  return encode(*m, ext, params);
+}
+
+MatVec4b MatVec3b_ToMatVec4b(MatVec3b m) {
+  cv::Mat_<cv::Vec4b>* dst = new cv::Mat_<cv::Vec4b>();
+  cv::cvtColor(*m, *dst, cv::COLOR_BGR2BGRA);
+  return dst;
+}
+
+MatVec3b MatVec3b_Region(MatVec3b m, struct Rect r) {
+  cv::Rect roi = cv::Rect(r.x, r.y, r.width, r.height) &
+    cv::Rect(0, 0, m->cols, m->rows);
+  if (roi.area() == 0) {
+    return new cv::Mat_<cv::Vec3b>();
+  }
+  return new cv::Mat_<cv::Vec3b>((*m)(roi).clone());
+}
+
+struct ByteArray Image_CvtColor(struct ByteArray src, int width, int height,
+    int channels, int code, int* dstChannels) {
+  // src is only referred while this function is running
+  cv::Mat img(height, width, CV_8UC(channels), src.data);
+  cv::Mat dst;
+  try {
+    cv::cvtColor(img, dst, code);
+  } catch (const cv::Exception&) {
+    *dstChannels = 0;
+    ByteArray empty = {NULL, 0};
+    return empty;
+  }
+  *dstChannels = dst.channels();
+  return toByteArray(reinterpret_cast<const char*>(dst.data),
+    dst.total() * dst.elemSize());
+}
+
+MatVec3b MatVec3b_Resize(MatVec3b m, int width, int height, int interpolation) {
+  cv::Mat_<cv::Vec3b>* dst = new cv::Mat_<cv::Vec3b>();
+  try {
+    cv::resize(*m, *dst, cv::Size(width, height), 0, 0, interpolation);
+  } catch (const cv::Exception&) {
+    dst->release();
+  }
+  return dst;
+}
+
+void MatVec3b_ResizeTo(MatVec3b src, MatVec3b dst, int width, int height,
+    int interpolation) {
+  try {
+    cv::resize(*src, *dst, cv::Size(width, height), 0, 0, interpolation);
+  } catch (const cv::Exception&) {
+    dst->release();
+  }
+}
+
+MatVec3b MatVec3b_CopyMakeBorder(MatVec3b m, int top, int bottom, int left,
+    int right) {
+  cv::Mat_<cv::Vec3b>* dst = new cv::Mat_<cv::Vec3b>();
+  try {
+    cv::copyMakeBorder(*m, *dst, top, bottom, left, right,
+      cv::BORDER_CONSTANT, cv::Scalar(0, 0, 0));
+  } catch (const cv::Exception&) {
+    dst->release();
+  }
+  return dst;
+}
+
+MatVec4b MatVec4b_New() {
+  return new cv::Mat_<cv::Vec4b>();
+}
+
 void MatVec4b_Delete(MatVec4b m) {
   delete m;
 }
 
-struct RawData MatVec4b_ToRawData(MatVec4b m) {
-  int width = m->cols;
-  int height = m->rows;
-  int size = width * height * 4;
-  char* data = reinterpret_cast<char*>(m->data);
-  ByteArray byteData = {data, size};
-  RawData raw = {width, height, byteData};
-  return raw;
+int MatVec4b_Empty(MatVec4b m) {
+  return m->empty();
 }
 
 MatVec4b RawData_ToMatVec4b(struct RawData r) {
-  int rows = r.height;
-  int cols = r.width;
-  cv::Mat_<cv::Vec4b>* mat = new cv::Mat_<cv::Vec4b>(rows, cols);
-  unsigned char* data = reinterpret_cast<unsigned char*>(r.data.data);
-  mat->data = data;
+  // r.data is Go memory which must not be referred after returning, so the
+  // Mat owns a copy of it.
+  cv::Mat_<cv::Vec4b>* mat = new cv::Mat_<cv::Vec4b>(r.height, r.width);
+  memcpy(mat->data, r.data.data, r.width * r.height * 4);
+  return mat;
+}
+
+struct ByteArray MatVec4b_Encode(MatVec4b m, const char* ext,
+    struct IntVector params) {
+  return encode(*m, ext, params);
+}
+
+MatVec3b MatVec4b_ToMatVec3b(MatVec4b m) {
+  cv::Mat_<cv::Vec3b>* dst = new cv::Mat_<cv::Vec3b>();
+  cv::cvtColor(*m, *dst, cv::COLOR_BGRA2BGR);
+  return dst;
+}
+
+MatGray MatGray_New() {
+  return new cv::Mat_<uchar>();
+}
+
+void MatGray_Delete(MatGray m) {
+  delete m;
+}
+
+int MatGray_Empty(MatGray m) {
+  return m->empty();
+}
+
+int MatGray_Cols(MatGray m) {
+  return m->cols;
+}
+
+int MatGray_Rows(MatGray m) {
+  return m->rows;
+}
+
+MatGray RawData_ToMatGray(struct RawData r) {
+  cv::Mat_<uchar>* mat = new cv::Mat_<uchar>(r.height, r.width);
+  memcpy(mat->data, r.data.data, r.width * r.height);
   return mat;
 }
 
+struct ByteArray MatGray_Encode(MatGray m, const char* ext,
+    struct IntVector params) {
+  return encode(*m, ext, params);
+}
+
+MatVec3b MatGray_ToMatVec3b(MatGray m) {
+  cv::Mat_<cv::Vec3b>* dst = new cv::Mat_<cv::Vec3b>();
+  cv::cvtColor(*m, *dst, cv::COLOR_GRAY2BGR);
+  return dst;
+}
+
+MatGray MatVec3b_ToMatGray(MatVec3b m) {
+  cv::Mat_<uchar>* dst = new cv::Mat_<uchar>();
+  cv::cvtColor(*m, *dst, cv::COLOR_BGR2GRAY);
+  return dst;
+}
+
+MatVec3b Image_DecodeToMatVec3b(struct ByteArray buf) {
+  if (buf.length <= 0) {
+    return new cv::Mat_<cv::Vec3b>();
+  }
+  cv::Mat raw(1, buf.length, CV_8UC1, buf.data);
+  cv::Mat img;
+  try {
+    img = cv::imdecode(raw, cv::IMREAD_COLOR);
+  } catch (const cv::Exception&) {
+    return new cv::Mat_<cv::Vec3b>();
+  }
+  return new cv::Mat_<cv::Vec3b>(img);
+}
+
+MatVec4b Image_DecodeToMatVec4b(struct ByteArray buf) {
+  if (buf.length <= 0) {
+    return new cv::Mat_<cv::Vec4b>();
+  }
+  cv::Mat raw(1, buf.length, CV_8UC1, buf.data);
+  cv::Mat img;
+  try {
+    img = cv::imdecode(raw, cv::IMREAD_UNCHANGED);
+  } catch (const cv::Exception&) {
+    return new cv::Mat_<cv::Vec4b>();
+  }
+  if (img.empty()) {
+    return new cv::Mat_<cv::Vec4b>();
+  }
+  if (img.depth() != CV_8U) {
+    cv::Mat tmp;
+    img.convertTo(tmp, CV_8U, 1.0/256);
+    img = tmp;
+  }
+  cv::Mat bgra;
+  switch (img.channels()) {
+  case 1:
+    cv::cvtColor(img, bgra, cv::COLOR_GRAY2BGRA);
+    break;
+  case 3:
+    cv::cvtColor(img, bgra, cv::COLOR_BGR2BGRA);
+    break;
+  case 2: {
+    // grayscale with alpha
+    cv::Mat ga[2];
+    cv::split(img, ga);
+    cv::Mat channels[] = {ga[0], ga[0], ga[0], ga[1]};
+    cv::merge(channels, 4, bgra);
+    break;
+  }
+  case 4:
+    bgra = img;
+    break;
+  default:
+    return new cv::Mat_<cv::Vec4b>();
+  }
+  return new cv::Mat_<cv::Vec4b>(bgra);
+}
+
+MatGray Image_DecodeToMatGray(struct ByteArray buf) {
+  if (buf.length <= 0) {
+    return new cv::Mat_<uchar>();
+  }
+  cv::Mat raw(1, buf.length, CV_8UC1, buf.data);
+  cv::Mat img;
+  try {
+    img = cv::imdecode(raw, cv::IMREAD_GRAYSCALE);
+  } catch (const cv::Exception&) {
+    return new cv::Mat_<uchar>();
+  }
+  return new cv::Mat_<uchar>(img);
+}
+
 VideoCapture VideoCapture_New() {
   return new cv::VideoCapture();
 }
@@ -93,6 +430,10 @@ void VideoCapture_Set(VideoCapture v, int prop, int param) {
   v->set(prop, param);
 }
 
+double VideoCapture_Get(VideoCapture v, int prop) {
+  return v->get(prop);
+}
+
 int VideoCapture_IsOpened(VideoCapture v) {
   return v->isOpened();
 }
@@ -115,14 +456,14 @@ void VideoWriter_Delete(VideoWriter vw) {
   delete vw;
 }
 
-void VideoWriter_Open(VideoWriter vw, const char* name, double fps, int width,
-    int height) {
-  vw->open(name, CV_FOURCC('M', 'J', 'P', 'G'), fps, cv::Size(width, height), true);
+void VideoWriter_Open(VideoWriter vw, const char* name, int fourcc, double fps,
+    int width, int height) {
+  vw->open(name, fourcc, fps, cv::Size(width, height), true);
 }
 
-void VideoWriter_OpenWithMat(VideoWriter vw, const char* name, double fps,
-    MatVec3b img) {
-  vw->open(name, CV_FOURCC('M', 'J', 'P', 'G'), fps, img->size(), true);
+void VideoWriter_OpenWithMat(VideoWriter vw, const char* name, int fourcc,
+    double fps, MatVec3b img) {
+  vw->open(name, fourcc, fps, img->size(), true);
 }
 
 int VideoWriter_IsOpened(VideoWriter vw) {
@@ -145,20 +486,145 @@ int CascadeClassifier_Load(CascadeClassifier cs, const char* name) {
   return cs->load(name);
 }
 
-struct Rects CascadeClassifier_DetectMultiScale(CascadeClassifier cs, MatVec3b img) {
+// Restoration is used to map rectangles on the converted image back to the
+// original image.
+struct Restoration {
+  double scaleX;
+  double scaleY;
+  int offsetX;
+  int offsetY;
+};
+
+// prepareDetection converts the image before detection, and sets the
+// restoration to map detected rectangles back to the original image. Returned
+// image is empty when the region of interest is out of the image.
+static cv::Mat prepareDetection(const cv::Mat& img,
+    struct DetectMultiScaleParams& params, Restoration* rs) {
+  cv::Mat dst = img;
+  rs->scaleX = 1;
+  rs->scaleY = 1;
+  rs->offsetX = 0;
+  rs->offsetY = 0;
+  if (params.roi.width > 0 && params.roi.height > 0) {
+    cv::Rect roi = cv::Rect(params.roi.x, params.roi.y, params.roi.width,
+      params.roi.height) & cv::Rect(0, 0, img.cols, img.rows);
+    if (roi.area() == 0) {
+      return cv::Mat();
+    }
+    dst = img(roi);
+    rs->offsetX = roi.x;
+    rs->offsetY = roi.y;
+  }
+  // the image is not downscaled when the size would be less than 1px, since
+  // cv::resize asserts the size
+  cv::Size size(cvRound(dst.cols / params.downscale),
+    cvRound(dst.rows / params.downscale));
+  if (params.downscale > 1 && size.width >= 1 && size.height >= 1) {
+    cv::Mat resized;
+    cv::resize(dst, resized, size, 0, 0, cv::INTER_AREA);
+    if (!resized.empty()) {
+      rs->scaleX = (double)dst.cols / resized.cols;
+      rs->scaleY = (double)dst.rows / resized.rows;
+      dst = resized;
+      params.minSize.width /= rs->scaleX;
+      params.minSize.height /= rs->scaleY;
+      params.maxSize.width /= rs->scaleX;
+      params.maxSize.height /= rs->scaleY;
+    }
+  }
+  if (params.grayscale || params.equalization != EQUALIZATION_NONE) {
+    cv::Mat gray;
+    cv::cvtColor(dst, gray, cv::COLOR_BGR2GRAY);
+    dst = gray;
+  }
+  if (params.equalization == EQUALIZATION_HIST) {
+    cv::Mat equalized;
+    cv::equalizeHist(dst, equalized);
+    dst = equalized;
+  } else if (params.equalization == EQUALIZATION_CLAHE) {
+    cv::Ptr<cv::CLAHE> clahe = cv::createCLAHE(params.claheClipLimit,
+      cv::Size(params.claheTileSize, params.claheTileSize));
+    cv::Mat equalized;
+    clahe->apply(dst, equalized);
+    dst = equalized;
+  }
+  return dst;
+}
+
+static Rect restoreRect(const cv::Rect& r, const Restoration& rs) {
+  Rect ret = {(int)(r.x * rs.scaleX) + rs.offsetX,
+    (int)(r.y * rs.scaleY) + rs.offsetY,
+    (int)(r.width * rs.scaleX), (int)(r.height * rs.scaleY)};
+  return ret;
+}
+
+struct Rects CascadeClassifier_DetectMultiScale(CascadeClassifier cs, MatVec3b img,
+    struct DetectMultiScaleParams params, int* ok) {
+  Restoration rs;
   std::vector<cv::Rect> faces;
-  cs->detectMultiScale(*img, faces); // TODO control default parameter
+  try {
+    cv::Mat target = prepareDetection(*img, params, &rs);
+    if (!target.empty()) {
+      cs->detectMultiScale(target, faces, params.scaleFactor,
+        params.minNeighbors, params.flags,
+        cv::Size(params.minSize.width, params.minSize.height),
+        cv::Size(params.maxSize.width, params.maxSize.height));
+    }
+  } catch (const cv::Exception&) {
+    *ok = 0;
+    Rects empty = {NULL, 0};
+    return empty;
+  }
+  *ok = 1;
   Rect* rects = new Rect[faces.size()];
   for (size_t i = 0; i < faces.size(); ++i) {
-    Rect r = {faces[i].x, faces[i].y, faces[i].width, faces[i].height};
-    rects[i] = r;
+    rects[i] = restoreRect(faces[i], rs);
   }
   Rects ret = {rects, (int)faces.size()};
   return ret;
 }
 
+struct WeightedRects CascadeClassifier_DetectMultiScaleWithWeights(
+    CascadeClassifier cs, MatVec3b img, struct DetectMultiScaleParams params,
+    int* ok) {
+  Restoration rs;
+  std::vector<cv::Rect> objects;
+  std::vector<int> rejectLevels;
+  std::vector<double> levelWeights;
+  try {
+    cv::Mat target = prepareDetection(*img, params, &rs);
+    if (!target.empty()) {
+      cs->detectMultiScale(target, objects, rejectLevels, levelWeights,
+        params.scaleFactor, params.minNeighbors, params.flags,
+        cv::Size(params.minSize.width, params.minSize.height),
+        cv::Size(params.maxSize.width, params.maxSize.height), true);
+    }
+  } catch (const cv::Exception&) {
+    *ok = 0;
+    WeightedRects empty = {NULL, NULL, NULL, 0};
+    return empty;
+  }
+  *ok = 1;
+  Rect* rects = new Rect[objects.size()];
+  int* levels = new int[objects.size()];
+  double* weights = new double[objects.size()];
+  for (size_t i = 0; i < objects.size(); ++i) {
+    rects[i] = restoreRect(objects[i], rs);
+    levels[i] = i < rejectLevels.size() ? rejectLevels[i] : 0;
+    weights[i] = i < levelWeights.size() ? levelWeights[i] : 0;
+  }
+  WeightedRects ret = {rects, levels, weights, (int)objects.size()};
+  return ret;
+}
+
 void Rects_Delete(struct Rects rs) {
-  delete rs.rects;
+  delete[] rs.rects;
+}
+
+void WeightedRects_Delete(struct WeightedRects rs) {
+  delete[] rs.rects;
+  delete[] rs.levels;
+  delete[] rs.weights;
 }
 
 void DrawRectsToImage(MatVec3b img, struct Rects rects) {
@@ -231,3 +697,64 @@ void MountAlphaImage(MatVec4b img, MatVec3b back, struct Rects rects) {
       + back->mul(img_backa, 1.0/(float)maxVal);
   }
 }
+
+HOGDescriptor HOGDescriptor_New() {
+  return new cv::HOGDescriptor();
+}
+
+void HOGDescriptor_Delete(HOGDescriptor hog) {
+  delete hog;
+}
+
+int HOGDescriptor_Load(HOGDescriptor hog, const char* name) {
+  try {
+    return hog->load(name);
+  } catch (const cv::Exception&) {
+    // e.g. the size of the SVM detector does not match the window
+    return 0;
+  }
+}
+
+void HOGDescriptor_SetDefaultPeopleDetector(HOGDescriptor hog) {
+  hog->setSVMDetector(cv::HOGDescriptor::getDefaultPeopleDetector());
+}
+
+struct WeightedRects HOGDescriptor_DetectMultiScale(HOGDescriptor hog,
+    MatVec3b img, struct HOGDetectParams params, int* ok) {
+  std::vector<cv::Rect> found;
+  std::vector<double> weights;
+  try {
+    hog->detectMultiScale(*img, found, weights, params.hitThreshold,
+      cv::Size(params.winStride.width, params.winStride.height),
+      cv::Size(params.padding.width, params.padding.height), params.scale,
+      params.finalThreshold);
+  } catch (const cv::Exception&) {
+    *ok = 0;
+    WeightedRects empty = {NULL, NULL, NULL, 0};
+    return empty;
+  }
+  *ok = 1;
+  Rect* rects = new Rect[found.size()];
+  int* levels = new int[found.size()];
+  double* ws = new double[found.size()];
+  for (size_t i = 0; i < found.size(); ++i) {
+    Rect r = {found[i].x, found[i].y, found[i].width, found[i].height};
+    rects[i] = r;
+    levels[i] = 0;
+    ws[i] = i < weights.size() ? weights[i] : 0;
+  }
+  WeightedRects ret = {rects, levels, ws, (int)found.size()};
+  return ret;
+}
+
+void IntVector_Delete(struct IntVector v) {
+  delete[] v.val;
+}
+
+long long TickCount() {
+  return cv::getTickCount();
+}
+
+double TickFrequency() {
+  return cv::getTickFrequency();
+}
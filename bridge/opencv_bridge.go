@@ -11,10 +11,14 @@ import "C"
 import (
 	"reflect"
 	"sync"
+	"time"
 	"unsafe"
 )
 
 const (
+	// CvCapPropPosMsec is OpenCV parameter of the position of the current
+	// frame in milliseconds
+	CvCapPropPosMsec = 0
 	// CvCapPropFrameWidth is OpenCV parameter of Frame Width
 	CvCapPropFrameWidth = 3
 	// CvCapPropFrameHeight is OpenCV parameter of Frame Height
@@ -23,6 +27,33 @@ const (
 	CvCapPropFps = 5
 )
 
+const (
+	// CvImwriteJpegQuality is OpenCV parameter of JPEG quality (0-100)
+	CvImwriteJpegQuality = 1
+	// CvImwritePngCompression is OpenCV parameter of PNG compression level
+	// (0-9)
+	CvImwritePngCompression = 16
+	// CvImwriteWebpQuality is OpenCV parameter of WebP quality (1-100)
+	CvImwriteWebpQuality = 64
+)
+
+// FourCCMJPG is the four character code of Motion JPEG codec, which is used
+// by VideoWriter as default.
+var FourCCMJPG = FourCC('M', 'J', 'P', 'G')
+
+// FourCC returns a four character code of a codec, same as `CV_FOURCC` macro.
+func FourCC(c1, c2, c3, c4 byte) int {
+	return int(c1) | int(c2)<<8 | int(c3)<<16 | int(c4)<<24
+}
+
+// MonotonicNow returns the current time of a monotonic clock with
+// `cv::getTickCount`, which is not affected by the wall clock adjustment.
+// Only differences between returned values are meaningful.
+func MonotonicNow() time.Duration {
+	ticks := float64(C.TickCount())
+	return time.Duration(ticks / float64(C.TickFrequency()) * float64(time.Second))
+}
+
 // CMatVec3b is an alias for C pointer.
 type CMatVec3b C.MatVec3b
 
@@ -70,15 +101,30 @@ func (m *MatVec3b) Empty() bool {
 	return isEmpty != 0
 }
 
+// Cols returns the number of columns (= width) of the MatVec3b.
+func (m *MatVec3b) Cols() int {
+	return int(C.MatVec3b_Cols(m.p))
+}
+
+// Rows returns the number of rows (= height) of the MatVec3b.
+func (m *MatVec3b) Rows() int {
+	return int(C.MatVec3b_Rows(m.p))
+}
+
 // ToRawData converts MatVec3b to RawData.
 func (m *MatVec3b) ToRawData() (int, int, []byte) {
-	r := C.MatVec3b_ToRawData(m.p)
-	return int(r.width), int(r.height), toGoBytes(r.data)
+	mat := m.ToMat()
+	defer mat.Delete()
+	return mat.toRawData()
 }
 
-// ToMatVec3b converts RawData to MatVec3b. Returned MatVec3b is required to
-// delete after using.
+// ToMatVec3b converts RawData to MatVec3b. The data is copied, so MatVec3b
+// does not refer the slice after returning. Returned MatVec3b is empty when
+// the data is shorter than the size, and required to delete after using.
 func ToMatVec3b(width int, height int, data []byte) MatVec3b {
+	if width <= 0 || height <= 0 || len(data) < width*height*3 {
+		return NewMatVec3b()
+	}
 	cr := C.struct_RawData{
 		width:  C.int(width),
 		height: C.int(height),
@@ -87,6 +133,129 @@ func ToMatVec3b(width int, height int, data []byte) MatVec3b {
 	return MatVec3b{p: C.RawData_ToMatVec3b(cr)}
 }
 
+// Encode encodes MatVec3b to the image format decided by ext (e.g. ".png").
+// params are pairs of OpenCV parameter ID and its value
+// (e.g. CvImwritePngCompression). Returns `false` when the linked OpenCV
+// cannot encode the format.
+func (m *MatVec3b) Encode(ext string, params []int) ([]byte, bool) {
+	cExt := C.CString(ext)
+	defer C.free(unsafe.Pointer(cExt))
+	b := C.MatVec3b_Encode(m.p, cExt, toIntVector(params))
+	if b.length == 0 {
+		return nil, false
+	}
+	defer C.ByteArray_Release(b)
+	return toGoBytes(b), true
+}
+
+// ToMatVec4b converts MatVec3b to MatVec4b, the alpha values are filled with
+// 255. Returned MatVec4b is required to delete after using.
+func (m *MatVec3b) ToMatVec4b() MatVec4b {
+	return MatVec4b{p: C.MatVec3b_ToMatVec4b(m.p)}
+}
+
+// Region returns a copy of the region of the rectangle, the rectangle is
+// clamped to the image. Returned MatVec3b is empty when the rectangle is out
+// of the image, and required to delete after using.
+func (m *MatVec3b) Region(r Rect) MatVec3b {
+	cRect := C.struct_Rect{
+		x:      C.int(r.X),
+		y:      C.int(r.Y),
+		width:  C.int(r.Width),
+		height: C.int(r.Height),
+	}
+	return MatVec3b{p: C.MatVec3b_Region(m.p, cRect)}
+}
+
+// Color conversion codes of CvtColor, the values are the same as OpenCV's.
+const (
+	// ColorBGR2BGRA adds alpha channel to BGR image.
+	ColorBGR2BGRA = 0
+	// ColorBGRA2BGR removes alpha channel from BGRA image.
+	ColorBGRA2BGR = 1
+	// ColorBGR2RGB swaps the first and the last channels.
+	ColorBGR2RGB = 4
+	// ColorRGB2BGR swaps the first and the last channels.
+	ColorRGB2BGR = 4
+	// ColorBGR2GRAY converts BGR image to grayscale.
+	ColorBGR2GRAY = 6
+	// ColorGRAY2BGR converts grayscale image to BGR.
+	ColorGRAY2BGR = 8
+	// ColorBGR2YCrCb converts BGR image to YCrCb.
+	ColorBGR2YCrCb = 36
+	// ColorYCrCb2BGR converts YCrCb image to BGR.
+	ColorYCrCb2BGR = 38
+	// ColorBGR2HSV converts BGR image to HSV, H is in [0, 180).
+	ColorBGR2HSV = 40
+	// ColorHSV2BGR converts HSV image to BGR.
+	ColorHSV2BGR = 54
+)
+
+// CvtColor converts 8-bit image data which has the channels with
+// `cv::cvtColor`, and returns converted data and its number of channels.
+// Returns `false` when the conversion fails, e.g. the code does not match the
+// channels.
+func CvtColor(b []byte, width, height, channels, code int) ([]byte, int,
+	bool) {
+	if width <= 0 || height <= 0 || len(b) < width*height*channels {
+		return nil, 0, false
+	}
+	var dstChannels C.int
+	ret := C.Image_CvtColor(toByteArray(b), C.int(width), C.int(height),
+		C.int(channels), C.int(code), &dstChannels)
+	if dstChannels == 0 {
+		return nil, 0, false
+	}
+	defer C.ByteArray_Release(ret)
+	return toGoBytes(ret), int(dstChannels), true
+}
+
+// Interpolation methods of Resize, the values are the same as OpenCV's.
+const (
+	// InterNearest is a nearest neighbor interpolation.
+	InterNearest = 0
+	// InterLinear is a bilinear interpolation.
+	InterLinear = 1
+	// InterCubic is a bicubic interpolation.
+	InterCubic = 2
+	// InterArea is a resampling using pixel area relation, which is preferred
+	// for shrinking.
+	InterArea = 3
+	// InterLanczos4 is a Lanczos interpolation over 8x8 neighborhood.
+	InterLanczos4 = 4
+)
+
+// Resize returns the image resized to the size with `cv::resize`. Returned
+// MatVec3b is empty when the image cannot be resized (e.g. the size is 0),
+// and required to delete after using.
+func (m *MatVec3b) Resize(width, height, interpolation int) MatVec3b {
+	return MatVec3b{p: C.MatVec3b_Resize(m.p, C.int(width), C.int(height),
+		C.int(interpolation))}
+}
+
+// ResizeTo resizes the image to the size with `cv::resize`, and writes it to
+// dst. The buffer of dst is reused when its size is the same. dst is empty
+// when the image cannot be resized.
+func (m *MatVec3b) ResizeTo(dst MatVec3b, width, height, interpolation int) {
+	C.MatVec3b_ResizeTo(m.p, dst.p, C.int(width), C.int(height),
+		C.int(interpolation))
+}
+
+// CopyMakeBorder returns the image surrounded by black borders of each
+// width. Returned MatVec3b is empty when a width is negative, and required to
+// delete after using.
+func (m *MatVec3b) CopyMakeBorder(top, bottom, left, right int) MatVec3b {
+	return MatVec3b{p: C.MatVec3b_CopyMakeBorder(m.p, C.int(top),
+		C.int(bottom), C.int(left), C.int(right))}
+}
+
+// DecodeToMatVec3b decodes image data (e.g. JPEG, PNG) to MatVec3b. Returned
+// MatVec3b is empty when the data cannot be decoded, and required to delete
+// after using.
+func DecodeToMatVec3b(buf []byte) MatVec3b {
+	return MatVec3b{p: C.Image_DecodeToMatVec3b(toByteArray(buf))}
+}
+
 // MatVec4b is a bind of `cv::Mat_<cv::Vec4b>`
 type MatVec4b struct {
 	p C.MatVec4b
@@ -98,15 +267,54 @@ func (m *MatVec4b) Delete() {
 	m.p = nil
 }
 
+// Empty returns the MatVec4b is empty or not.
+func (m *MatVec4b) Empty() bool {
+	isEmpty := C.MatVec4b_Empty(m.p)
+	return isEmpty != 0
+}
+
+// Encode encodes MatVec4b to the image format decided by ext (e.g. ".png").
+// See MatVec3b.Encode for params.
+func (m *MatVec4b) Encode(ext string, params []int) ([]byte, bool) {
+	cExt := C.CString(ext)
+	defer C.free(unsafe.Pointer(cExt))
+	b := C.MatVec4b_Encode(m.p, cExt, toIntVector(params))
+	if b.length == 0 {
+		return nil, false
+	}
+	defer C.ByteArray_Release(b)
+	return toGoBytes(b), true
+}
+
+// ToMatVec3b converts MatVec4b to MatVec3b, the alpha channel is dropped.
+// Returned MatVec3b is required to delete after using.
+func (m *MatVec4b) ToMatVec3b() MatVec3b {
+	return MatVec3b{p: C.MatVec4b_ToMatVec3b(m.p)}
+}
+
+// DecodeToMatVec4b decodes image data (e.g. PNG) to MatVec4b. When the image
+// does not have alpha channel, the alpha values are filled with 255, and
+// grayscale with alpha is expanded to BGRA. Returned MatVec4b is empty when
+// the data cannot be decoded or has an unsupported number of channels, and
+// required to delete after using.
+func DecodeToMatVec4b(buf []byte) MatVec4b {
+	return MatVec4b{p: C.Image_DecodeToMatVec4b(toByteArray(buf))}
+}
+
 // ToRawData converts MatVec4b to RawData.
 func (m *MatVec4b) ToRawData() (int, int, []byte) {
-	r := C.MatVec4b_ToRawData(m.p)
-	return int(r.width), int(r.height), toGoBytes(r.data)
+	mat := m.ToMat()
+	defer mat.Delete()
+	return mat.toRawData()
 }
 
-// ToMatVec4b converts RawData to MatVec4b. Returned MatVec4b is required to
-// delete after using.
+// ToMatVec4b converts RawData to MatVec4b. The data is copied, so MatVec4b
+// does not refer the slice after returning. Returned MatVec4b is empty when
+// the data is shorter than the size, and required to delete after using.
 func ToMatVec4b(width int, height int, data []byte) MatVec4b {
+	if width <= 0 || height <= 0 || len(data) < width*height*4 {
+		return MatVec4b{p: C.MatVec4b_New()}
+	}
 	cr := C.struct_RawData{
 		width:  C.int(width),
 		height: C.int(height),
@@ -115,6 +323,105 @@ func ToMatVec4b(width int, height int, data []byte) MatVec4b {
 	return MatVec4b{p: C.RawData_ToMatVec4b(cr)}
 }
 
+// MatGray is a bind of `cv::Mat_<uchar>`, which has single channel.
+type MatGray struct {
+	p C.MatGray
+}
+
+// NewMatGray returns a new MatGray.
+func NewMatGray() MatGray {
+	return MatGray{p: C.MatGray_New()}
+}
+
+// Delete object.
+func (m *MatGray) Delete() {
+	C.MatGray_Delete(m.p)
+	m.p = nil
+}
+
+// Empty returns the MatGray is empty or not.
+func (m *MatGray) Empty() bool {
+	return C.MatGray_Empty(m.p) != 0
+}
+
+// Cols returns the number of columns (= width) of the MatGray.
+func (m *MatGray) Cols() int {
+	return int(C.MatGray_Cols(m.p))
+}
+
+// Rows returns the number of rows (= height) of the MatGray.
+func (m *MatGray) Rows() int {
+	return int(C.MatGray_Rows(m.p))
+}
+
+// ToRawData converts MatGray to RawData.
+func (m *MatGray) ToRawData() (int, int, []byte) {
+	mat := m.ToMat()
+	defer mat.Delete()
+	return mat.toRawData()
+}
+
+// Encode encodes MatGray to the image format decided by ext (e.g. ".png").
+// Returns `false` when the image cannot be encoded.
+func (m *MatGray) Encode(ext string, params []int) ([]byte, bool) {
+	cExt := C.CString(ext)
+	defer C.free(unsafe.Pointer(cExt))
+	b := C.MatGray_Encode(m.p, cExt, toIntVector(params))
+	if b.length == 0 {
+		return nil, false
+	}
+	defer C.ByteArray_Release(b)
+	return toGoBytes(b), true
+}
+
+// ToMatVec3b converts MatGray to MatVec3b, each channel has the same value.
+// Returned MatVec3b is required to delete after using.
+func (m *MatGray) ToMatVec3b() MatVec3b {
+	return MatVec3b{p: C.MatGray_ToMatVec3b(m.p)}
+}
+
+// ToMatGray converts MatVec3b to grayscale MatGray. Returned MatGray is
+// required to delete after using.
+func (m *MatVec3b) ToMatGray() MatGray {
+	return MatGray{p: C.MatVec3b_ToMatGray(m.p)}
+}
+
+// ToMatGray converts RawData to MatGray. The data is copied, so MatGray does
+// not refer the slice after returning. Returned MatGray is required to delete
+// after using.
+func ToMatGray(width int, height int, data []byte) MatGray {
+	if width <= 0 || height <= 0 || len(data) < width*height {
+		return NewMatGray()
+	}
+	cr := C.struct_RawData{
+		width:  C.int(width),
+		height: C.int(height),
+		data:   toByteArray(data),
+	}
+	return MatGray{p: C.RawData_ToMatGray(cr)}
+}
+
+// DecodeToMatGray decodes image data (e.g. PNG) to grayscale MatGray.
+// Returned MatGray is empty when the data cannot be decoded, and required to
+// delete after using.
+func DecodeToMatGray(buf []byte) MatGray {
+	return MatGray{p: C.Image_DecodeToMatGray(toByteArray(buf))}
+}
+
+func toIntVector(v []int) C.struct_IntVector {
+	if len(v) == 0 {
+		return C.struct_IntVector{}
+	}
+	cInts := make([]C.int, len(v))
+	for i, n := range v {
+		cInts[i] = C.int(n)
+	}
+	return C.struct_IntVector{
+		val:    &cInts[0],
+		length: C.int(len(v)),
+	}
+}
+
 // VideoCapture is a bind of `cv::VideoCapture`.
 type VideoCapture struct {
 	p C.VideoCapture
@@ -153,6 +460,12 @@ func (v *VideoCapture) Set(prop int, param int) {
 	C.VideoCapture_Set(v.p, C.int(prop), C.int(param))
 }
 
+// Get returns the value of the property (=key). Returns 0 when the property
+// is not supported by the backend.
+func (v *VideoCapture) Get(prop int) float64 {
+	return float64(C.VideoCapture_Get(v.p, C.int(prop)))
+}
+
 // IsOpened returns the video capture opens a file(or device) or not.
 func (v *VideoCapture) IsOpened() bool {
 	isOpened := C.VideoCapture_IsOpened(v.p)
@@ -187,18 +500,27 @@ func (vw *VideoWriter) Delete() {
 	vw.p = nil
 }
 
-// Open a video writer.
+// Open a video writer with Motion JPEG codec.
 func (vw *VideoWriter) Open(name string, fps float64, width int, height int) {
+	vw.OpenWithFourCC(name, FourCCMJPG, fps, width, height)
+}
+
+// OpenWithFourCC opens a video writer with the codec specified by fourcc.
+func (vw *VideoWriter) OpenWithFourCC(name string, fourcc int, fps float64,
+	width int, height int) {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
-	C.VideoWriter_Open(vw.p, cName, C.double(fps), C.int(width), C.int(height))
+	C.VideoWriter_Open(vw.p, cName, C.int(fourcc), C.double(fps), C.int(width),
+		C.int(height))
 }
 
-// OpenWithMat opens video writer.
+// OpenWithMat opens video writer with Motion JPEG codec. Frame size is
+// decided by the argument MatVec3b.
 func (vw *VideoWriter) OpenWithMat(name string, fps float64, img MatVec3b) {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
-	C.VideoWriter_OpenWithMat(vw.p, cName, C.double(fps), img.p)
+	C.VideoWriter_OpenWithMat(vw.p, cName, C.int(FourCCMJPG), C.double(fps),
+		img.p)
 }
 
 // IsOpened returns the video writer opens a file or not.
@@ -245,12 +567,186 @@ type Rect struct {
 	Height int
 }
 
+// Size represents size of rectangle.
+type Size struct {
+	Width  int
+	Height int
+}
+
+// Equalization is a type of histogram equalization.
+type Equalization int
+
+const (
+	// EqualizationNone does not equalize the image.
+	EqualizationNone Equalization = iota
+	// EqualizationHist equalizes the image with `cv::equalizeHist`.
+	EqualizationHist
+	// EqualizationCLAHE equalizes the image with `cv::CLAHE`.
+	EqualizationCLAHE
+)
+
+// DetectMultiScaleParams is parameters of
+// `cv::CascadeClassifier::detectMultiScale` and preprocessing of the image
+// before the detection.
+type DetectMultiScaleParams struct {
+	// ScaleFactor is how much the image size is reduced at each image scale,
+	// required to be greater than 1.
+	ScaleFactor float64
+	// MinNeighbors is how many neighbors each candidate rectangle should
+	// have to retain it.
+	MinNeighbors int
+	// Flags is OpenCV's flags, e.g. CV_HAAR_SCALE_IMAGE.
+	Flags int
+	// MinSize is minimum possible object size. Zero size means no limit.
+	MinSize Size
+	// MaxSize is maximum possible object size. Zero size means no limit.
+	MaxSize Size
+
+	// Grayscale converts the image to grayscale before the detection.
+	Grayscale bool
+	// Equalization equalizes histogram of the image before the detection.
+	// The image is converted to grayscale when the equalization is enabled.
+	Equalization Equalization
+	// CLAHEClipLimit is a threshold for contrast limiting of CLAHE.
+	CLAHEClipLimit float64
+	// CLAHETileSize is the size of grid for CLAHE.
+	CLAHETileSize int
+	// Downscale is a factor to downscale the image before the detection,
+	// e.g. 2 makes the image half size. The value 1 or less means no
+	// scaling, and the image is not downscaled when its downscaled size
+	// would be less than 1 pixel. Detected rectangles, MinSize and MaxSize
+	// are on the original image's coordinates.
+	Downscale float64
+	// ROI is a region of interest, the detection runs on the region. Zero
+	// size means the whole image. Detected rectangles are on the original
+	// image's coordinates.
+	ROI Rect
+}
+
+// DefaultDetectMultiScaleParams returns OpenCV's default parameters of
+// `cv::CascadeClassifier::detectMultiScale`.
+func DefaultDetectMultiScaleParams() DetectMultiScaleParams {
+	return DetectMultiScaleParams{
+		ScaleFactor:    1.1,
+		MinNeighbors:   3,
+		CLAHEClipLimit: 2,
+		CLAHETileSize:  8,
+		Downscale:      1,
+	}
+}
+
+func (p *DetectMultiScaleParams) toC() C.struct_DetectMultiScaleParams {
+	return C.struct_DetectMultiScaleParams{
+		scaleFactor:  C.double(p.ScaleFactor),
+		minNeighbors: C.int(p.MinNeighbors),
+		flags:        C.int(p.Flags),
+		minSize: C.struct_Size{
+			width:  C.int(p.MinSize.Width),
+			height: C.int(p.MinSize.Height),
+		},
+		maxSize: C.struct_Size{
+			width:  C.int(p.MaxSize.Width),
+			height: C.int(p.MaxSize.Height),
+		},
+		grayscale:      C.int(boolToInt(p.Grayscale)),
+		equalization:   C.int(p.Equalization),
+		claheClipLimit: C.double(p.CLAHEClipLimit),
+		claheTileSize:  C.int(p.CLAHETileSize),
+		downscale:      C.double(p.Downscale),
+		roi: C.struct_Rect{
+			x:      C.int(p.ROI.X),
+			y:      C.int(p.ROI.Y),
+			width:  C.int(p.ROI.Width),
+			height: C.int(p.ROI.Height),
+		},
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // DetectMultiScale detects something which is decided by loaded file. Returns
 // multi results addressed with rectangle.
 func (c *CascadeClassifier) DetectMultiScale(img MatVec3b) []Rect {
-	ret := C.CascadeClassifier_DetectMultiScale(c.p, img.p)
+	rects, _ := c.DetectMultiScaleWithParams(img,
+		DefaultDetectMultiScaleParams())
+	return rects
+}
+
+// DetectMultiScaleWithParams detects something which is decided by loaded
+// file with the parameters. Returns multi results addressed with rectangle.
+// Returns `false` when OpenCV fails to detect, e.g. the parameters are
+// invalid or the loaded file is broken.
+func (c *CascadeClassifier) DetectMultiScaleWithParams(img MatVec3b,
+	params DetectMultiScaleParams) ([]Rect, bool) {
+	var ok C.int
+	ret := C.CascadeClassifier_DetectMultiScale(c.p, img.p, params.toC(), &ok)
 	defer C.Rects_Delete(ret)
+	if ok == 0 {
+		return nil, false
+	}
+	return toGoRects(ret), true
+}
+
+// WeightedRect is a rectangle with its reject level and level weight, the
+// weight can be used as a confidence of the detection.
+type WeightedRect struct {
+	Rect
+	Level  int
+	Weight float64
+}
+
+// DetectMultiScaleWithWeights detects something like DetectMultiScaleWithParams,
+// and returns results with reject levels and level weights. Returns `false`
+// when OpenCV fails to detect.
+func (c *CascadeClassifier) DetectMultiScaleWithWeights(img MatVec3b,
+	params DetectMultiScaleParams) ([]WeightedRect, bool) {
+	var ok C.int
+	ret := C.CascadeClassifier_DetectMultiScaleWithWeights(c.p, img.p,
+		params.toC(), &ok)
+	defer C.WeightedRects_Delete(ret)
+	if ok == 0 {
+		return nil, false
+	}
+	return toGoWeightedRects(ret), true
+}
+
+func toGoWeightedRects(ret C.struct_WeightedRects) []WeightedRect {
+	length := int(ret.length)
+	rects := toGoRects(C.struct_Rects{
+		rects:  ret.rects,
+		length: ret.length,
+	})
 
+	lhdr := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(ret.levels)),
+		Len:  length,
+		Cap:  length,
+	}
+	levels := *(*[]C.int)(unsafe.Pointer(&lhdr))
+	whdr := reflect.SliceHeader{
+		Data: uintptr(unsafe.Pointer(ret.weights)),
+		Len:  length,
+		Cap:  length,
+	}
+	weights := *(*[]C.double)(unsafe.Pointer(&whdr))
+
+	wrects := make([]WeightedRect, length)
+	for i, r := range rects {
+		wrects[i] = WeightedRect{
+			Rect:   r,
+			Level:  int(levels[i]),
+			Weight: float64(weights[i]),
+		}
+	}
+	return wrects
+}
+
+func toGoRects(ret C.struct_Rects) []Rect {
 	cArray := ret.rects
 	length := int(ret.length)
 	hdr := reflect.SliceHeader{
@@ -272,6 +768,87 @@ func (c *CascadeClassifier) DetectMultiScale(img MatVec3b) []Rect {
 	return rects
 }
 
+// HOGDescriptor is a bind of `cv::HOGDescriptor`.
+type HOGDescriptor struct {
+	p C.HOGDescriptor
+}
+
+// NewHOGDescriptor returns a new HOGDescriptor with default parameters.
+func NewHOGDescriptor() HOGDescriptor {
+	return HOGDescriptor{p: C.HOGDescriptor_New()}
+}
+
+// Delete HOGDescriptor's pointer.
+func (h *HOGDescriptor) Delete() {
+	C.HOGDescriptor_Delete(h.p)
+	h.p = nil
+}
+
+// Load HOG descriptor configuration file which includes SVM detector.
+func (h *HOGDescriptor) Load(name string) bool {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	return C.HOGDescriptor_Load(h.p, cName) != 0
+}
+
+// SetDefaultPeopleDetector sets OpenCV's default people detector as SVM
+// detector.
+func (h *HOGDescriptor) SetDefaultPeopleDetector() {
+	C.HOGDescriptor_SetDefaultPeopleDetector(h.p)
+}
+
+// HOGDetectParams is parameters of `cv::HOGDescriptor::detectMultiScale`.
+type HOGDetectParams struct {
+	// HitThreshold is threshold for the distance between features and SVM
+	// classifying plane.
+	HitThreshold float64
+	// WinStride is window stride. Zero size means OpenCV's default.
+	WinStride Size
+	// Padding is padding of the image. Zero size means OpenCV's default.
+	Padding Size
+	// Scale is coefficient of the detection window increase.
+	Scale float64
+	// FinalThreshold is threshold to group detected rectangles.
+	FinalThreshold float64
+}
+
+// DefaultHOGDetectParams returns OpenCV's default parameters of
+// `cv::HOGDescriptor::detectMultiScale`.
+func DefaultHOGDetectParams() HOGDetectParams {
+	return HOGDetectParams{
+		Scale:          1.05,
+		FinalThreshold: 2,
+	}
+}
+
+// DetectMultiScale detects objects, e.g. people with the default people
+// detector. Returns multi results addressed with rectangle and its weight.
+// Level of the results is always 0. Returns `false` when OpenCV fails to
+// detect with the configuration or the parameters.
+func (h *HOGDescriptor) DetectMultiScale(img MatVec3b,
+	params HOGDetectParams) ([]WeightedRect, bool) {
+	cParams := C.struct_HOGDetectParams{
+		hitThreshold: C.double(params.HitThreshold),
+		winStride: C.struct_Size{
+			width:  C.int(params.WinStride.Width),
+			height: C.int(params.WinStride.Height),
+		},
+		padding: C.struct_Size{
+			width:  C.int(params.Padding.Width),
+			height: C.int(params.Padding.Height),
+		},
+		scale:          C.double(params.Scale),
+		finalThreshold: C.double(params.FinalThreshold),
+	}
+	var ok C.int
+	ret := C.HOGDescriptor_DetectMultiScale(h.p, img.p, cParams, &ok)
+	defer C.WeightedRects_Delete(ret)
+	if ok == 0 {
+		return nil, false
+	}
+	return toGoWeightedRects(ret), true
+}
+
 // DrawRectsToImage draws rectangle information to target image.
 func DrawRectsToImage(img MatVec3b, rects []Rect) {
 	cRectArray := make([]C.struct_Rect, len(rects))
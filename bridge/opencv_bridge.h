@@ -23,32 +23,144 @@ typedef struct Rects {
   Rect* rects;
   int length;
 } Rects;
+typedef struct WeightedRects {
+  Rect* rects;
+  int* levels;
+  double* weights;
+  int length;
+} WeightedRects;
+typedef struct Size {
+  int width;
+  int height;
+} Size;
+typedef struct Scalar {
+  double val[4];
+} Scalar;
+enum {
+  EQUALIZATION_NONE = 0,
+  EQUALIZATION_HIST = 1,
+  EQUALIZATION_CLAHE = 2
+};
+typedef struct DetectMultiScaleParams {
+  double scaleFactor;
+  int minNeighbors;
+  int flags;
+  Size minSize;
+  Size maxSize;
+  int grayscale;
+  int equalization;
+  double claheClipLimit;
+  int claheTileSize;
+  double downscale;
+  Rect roi;
+} DetectMultiScaleParams;
+typedef struct HOGDetectParams {
+  double hitThreshold;
+  Size winStride;
+  Size padding;
+  double scale;
+  double finalThreshold;
+} HOGDetectParams;
+typedef struct IntVector {
+  int* val;
+  int length;
+} IntVector;
 
 #ifdef __cplusplus
+typedef cv::Mat* Mat;
 typedef cv::Mat_<cv::Vec3b>* MatVec3b;
 typedef cv::Mat_<cv::Vec4b>* MatVec4b;
+typedef cv::Mat_<uchar>* MatGray;
 typedef cv::VideoCapture* VideoCapture;
 typedef cv::VideoWriter* VideoWriter;
 typedef cv::CascadeClassifier* CascadeClassifier;
+typedef cv::HOGDescriptor* HOGDescriptor;
 #else
+typedef void* Mat;
 typedef void* MatVec3b;
 typedef void* MatVec4b;
+typedef void* MatGray;
 typedef void* VideoCapture;
 typedef void* VideoWriter;
 typedef void* CascadeClassifier;
+typedef void* HOGDescriptor;
 #endif
 
+typedef struct Mats {
+  Mat* mats;
+  int length;
+} Mats;
+
+Mat Mat_New();
+Mat Mat_NewFromBytes(int rows, int cols, int type, struct ByteArray buf);
+void Mat_Delete(Mat m);
+void Mats_Delete(struct Mats ms);
+int Mat_Empty(Mat m);
+int Mat_Rows(Mat m);
+int Mat_Cols(Mat m);
+int Mat_Type(Mat m);
+int Mat_Depth(Mat m);
+int Mat_Channels(Mat m);
+struct IntVector Mat_Shape(Mat m);
+int Mat_IsContinuous(Mat m);
+Mat Mat_Clone(Mat m);
+// Mat_Data returns a view of elements of the continuous Mat, which is not
+// owned by the ByteArray (i.e. must not be released), and valid while the Mat
+// is alive.
+struct ByteArray Mat_Data(Mat m);
+Mat Mat_ConvertTo(Mat m, int type, double alpha, double beta);
+Mat Mat_Resize(Mat m, int width, int height, int interpolation);
+Mat Mat_CopyMakeBorder(Mat m, int top, int bottom, int left, int right,
+  struct Scalar value);
+MatVec3b Mat_ToMatVec3b(Mat m);
+MatVec4b Mat_ToMatVec4b(Mat m);
+MatGray Mat_ToMatGray(Mat m);
+Mat MatVec3b_ToMat(MatVec3b m);
+Mat MatVec4b_ToMat(MatVec4b m);
+Mat MatGray_ToMat(MatGray m);
+
 MatVec3b MatVec3b_New();
 struct ByteArray MatVec3b_ToJpegData(MatVec3b m, int quality);
 void MatVec3b_Delete(MatVec3b m);
 void MatVec3b_CopyTo(MatVec3b src, MatVec3b dst);
 int MatVec3b_Empty(MatVec3b m);
-struct RawData MatVec3b_ToRawData(MatVec3b m);
+int MatVec3b_Cols(MatVec3b m);
+int MatVec3b_Rows(MatVec3b m);
 MatVec3b RawData_ToMatVec3b(struct RawData r);
+struct ByteArray MatVec3b_Encode(MatVec3b m, const char* ext,
+  struct IntVector params);
+MatVec4b MatVec3b_ToMatVec4b(MatVec3b m);
+MatVec3b MatVec3b_Region(MatVec3b m, struct Rect r);
+struct ByteArray Image_CvtColor(struct ByteArray src, int width, int height,
+  int channels, int code, int* dstChannels);
+MatVec3b MatVec3b_Resize(MatVec3b m, int width, int height, int interpolation);
+void MatVec3b_ResizeTo(MatVec3b src, MatVec3b dst, int width, int height,
+  int interpolation);
+MatVec3b MatVec3b_CopyMakeBorder(MatVec3b m, int top, int bottom, int left,
+  int right);
 
+MatVec4b MatVec4b_New();
 void MatVec4b_Delete(MatVec4b m);
-struct RawData MatVec4b_ToRawData(MatVec4b m);
+int MatVec4b_Empty(MatVec4b m);
 MatVec4b RawData_ToMatVec4b(struct RawData r);
+struct ByteArray MatVec4b_Encode(MatVec4b m, const char* ext,
+  struct IntVector params);
+MatVec3b MatVec4b_ToMatVec3b(MatVec4b m);
+
+MatGray MatGray_New();
+void MatGray_Delete(MatGray m);
+int MatGray_Empty(MatGray m);
+int MatGray_Cols(MatGray m);
+int MatGray_Rows(MatGray m);
+MatGray RawData_ToMatGray(struct RawData r);
+struct ByteArray MatGray_Encode(MatGray m, const char* ext,
+  struct IntVector params);
+MatVec3b MatGray_ToMatVec3b(MatGray m);
+MatGray MatVec3b_ToMatGray(MatVec3b m);
+
+MatVec3b Image_DecodeToMatVec3b(struct ByteArray buf);
+MatVec4b Image_DecodeToMatVec4b(struct ByteArray buf);
+MatGray Image_DecodeToMatGray(struct ByteArray buf);
 
 VideoCapture VideoCapture_New();
 void VideoCapture_Delete(VideoCapture v);
@@ -56,28 +168,46 @@ int VideoCapture_Open(VideoCapture v, const char* uri);
 int VideoCapture_OpenDevice(VideoCapture v, int device);
 void VideoCapture_Release(VideoCapture v);
 void VideoCapture_Set(VideoCapture v, int prop, int param);
+double VideoCapture_Get(VideoCapture v, int prop);
 int VideoCapture_IsOpened(VideoCapture v);
 int VideoCapture_Read(VideoCapture v, MatVec3b buf);
 void VideoCapture_Grab(VideoCapture v, int skip);
 
 VideoWriter VideoWriter_New();
 void VideoWriter_Delete(VideoWriter vw);
-void VideoWriter_Open(VideoWriter vw, const char* name, double fps, int width,
-  int height);
-void VideoWriter_OpenWithMat(VideoWriter vw, const char* name, double fps,
-  MatVec3b img);
+void VideoWriter_Open(VideoWriter vw, const char* name, int fourcc, double fps,
+  int width, int height);
+void VideoWriter_OpenWithMat(VideoWriter vw, const char* name, int fourcc,
+  double fps, MatVec3b img);
 int VideoWriter_IsOpened(VideoWriter vw);
 void VideoWriter_Write(VideoWriter vw, MatVec3b img);
 
 CascadeClassifier CascadeClassifier_New();
 void CascadeClassifier_Delete(CascadeClassifier cs);
 int CascadeClassifier_Load(CascadeClassifier cs, const char* name);
-struct Rects CascadeClassifier_DetectMultiScale(CascadeClassifier cs, MatVec3b img);
+struct Rects CascadeClassifier_DetectMultiScale(CascadeClassifier cs, MatVec3b img,
+  struct DetectMultiScaleParams params, int* ok);
+struct WeightedRects CascadeClassifier_DetectMultiScaleWithWeights(
+  CascadeClassifier cs, MatVec3b img, struct DetectMultiScaleParams params,
+  int* ok);
 void Rects_Delete(struct Rects rs);
+void WeightedRects_Delete(struct WeightedRects rs);
 void DrawRectsToImage(MatVec3b img, struct Rects rects);
 MatVec4b LoadAlphaImg(const char* name);
 void MountAlphaImage(MatVec4b img, MatVec3b back, struct Rects rects);
 
+HOGDescriptor HOGDescriptor_New();
+void HOGDescriptor_Delete(HOGDescriptor hog);
+int HOGDescriptor_Load(HOGDescriptor hog, const char* name);
+void HOGDescriptor_SetDefaultPeopleDetector(HOGDescriptor hog);
+struct WeightedRects HOGDescriptor_DetectMultiScale(HOGDescriptor hog,
+  MatVec3b img, struct HOGDetectParams params, int* ok);
+
+void IntVector_Delete(struct IntVector v);
+
+long long TickCount();
+double TickFrequency();
+
 #ifdef __cplusplus
 }
 #endif
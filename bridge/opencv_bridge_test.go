@@ -0,0 +1,54 @@
+package bridge
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Tests in this package are also run with GODEBUG=cgocheck=2 on CI (see
+// wercker.yml), which detects Go pointers stored into C memory.
+func TestToMatVec3b(t *testing.T) {
+	Convey("Given a 2x1 image data", t, func() {
+		b := []byte{1, 2, 3, 4, 5, 6}
+		m := ToMatVec3b(2, 1, b)
+		Reset(func() {
+			m.Delete()
+		})
+		Convey("When modify the data after converting it", func() {
+			for i := range b {
+				b[i] = 0
+			}
+			b = nil
+			runtime.GC()
+			Convey("Then MatVec3b should keep the original elements", func() {
+				w, h, d := m.ToRawData()
+				So(w, ShouldEqual, 2)
+				So(h, ShouldEqual, 1)
+				So(d, ShouldResemble, []byte{1, 2, 3, 4, 5, 6})
+			})
+		})
+	})
+
+	Convey("Given image data shorter than the size", t, func() {
+		m := ToMatVec3b(2, 2, []byte{1, 2, 3})
+		defer m.Delete()
+		Convey("Then MatVec3b should be empty", func() {
+			So(m.Empty(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestMonotonicNow(t *testing.T) {
+	Convey("Given the current time of the monotonic clock", t, func() {
+		start := MonotonicNow()
+		Convey("When sleep a while", func() {
+			time.Sleep(10 * time.Millisecond)
+			Convey("Then the clock should advance at least the duration", func() {
+				So(MonotonicNow()-start, ShouldBeGreaterThanOrEqualTo,
+					10*time.Millisecond)
+			})
+		})
+	})
+}
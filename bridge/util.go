@@ -8,7 +8,14 @@ import (
 	"unsafe"
 )
 
+// toByteArray returns ByteArray which refers the Go memory of the slice
+// without copying. Following cgo pointer passing rules, C/C++ functions
+// receiving it must not keep the pointer after returning, i.e. they copy the
+// data when they need it later.
 func toByteArray(b []byte) C.struct_ByteArray {
+	if len(b) == 0 {
+		return C.struct_ByteArray{}
+	}
 	return C.struct_ByteArray{
 		data:   (*C.char)(unsafe.Pointer(&b[0])),
 		length: C.int(len(b)),
@@ -25,7 +25,20 @@ var (
 //
 // device_id: [required] The ID of associated device.
 //
-// format: Output format style, default is "cvmat".
+// format: Output format style, "cvmat" or "jpeg", default is "cvmat".
+//
+// jpeg_quality: The quality of JPEG encoding from 0 to 100, default is 95.
+// This parameter is used only when format is "jpeg".
+//
+// resize_width: Width of output frames, which is different from width for
+// the device. If only one of resize_width and resize_height is set then the
+// other is decided with keeping the aspect ratio. Frames are resized before
+// they are converted to tuples.
+//
+// resize_height: Height of output frames, see resize_width.
+//
+// scale: Scale factor of output frames (e.g. 0.5), which cannot be set with
+// resize_width or resize_height.
 //
 // width: Frame width, if set empty or "0" then will be ignore.
 //
@@ -55,11 +68,14 @@ func (c *FromDeviceCreator) createCaptureFromDevice(ctx *core.Context,
 		return nil, err
 	}
 
-	format := "cvmat"
-	if fm, err := params.Get(formatPath); err == nil {
-		if format, err = data.AsString(fm); err != nil {
-			return nil, err
-		}
+	formatFunc, err := newFormatFunc(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resizer, err := newFrameResizer(params)
+	if err != nil {
+		return nil, err
 	}
 
 	w, err := params.Get(widthPath)
@@ -90,15 +106,12 @@ func (c *FromDeviceCreator) createCaptureFromDevice(ctx *core.Context,
 	}
 
 	cs := &captureFromDevice{
-		deviceID: deviceID,
-		width:    width,
-		height:   height,
-		fps:      fps,
-	}
-	if format == "cvmat" {
-		cs.formatFunc = toRawMap
-	} else {
-		return nil, fmt.Errorf("'%v' format is not supported", format)
+		deviceID:   deviceID,
+		width:      width,
+		height:     height,
+		fps:        fps,
+		formatFunc: formatFunc,
+		resizer:    resizer,
 	}
 	return cs, nil
 }
@@ -109,6 +122,7 @@ type captureFromDevice struct {
 	height     int64
 	fps        int64
 	formatFunc func(m *bridge.MatVec3b) data.Map
+	resizer    *frameResizer
 }
 
 // GenerateStream streams video capture data. OpenCV parameters
@@ -118,7 +132,8 @@ type captureFromDevice struct {
 //
 // format: The frame's format style, ex) "cvmat", "jpeg",...
 //
-// mode: The frame's format mode, ex) "BGR", "RGBA",...
+// mode: The frame's color mode, "BGR" for "cvmat". This field is not set for
+// compressed formats (e.g. "jpeg").
 //
 // width: The frame's width.
 //
@@ -147,6 +162,8 @@ func (c *captureFromDevice) GenerateStream(ctx *core.Context, w core.Writer) err
 	// streaming, capture from vcap
 	buf := bridge.NewMatVec3b()
 	defer buf.Delete()
+	resized := bridge.NewMatVec3b()
+	defer resized.Delete()
 	ctx.Log().Infof("start reading camera device: %v", c.deviceID)
 	for {
 		if ok := vcap.Read(buf); !ok {
@@ -156,7 +173,12 @@ func (c *captureFromDevice) GenerateStream(ctx *core.Context, w core.Writer) err
 			continue
 		}
 
-		m := c.formatFunc(&buf)
+		frame := &buf
+		if c.resizer != nil {
+			c.resizer.resize(buf, resized)
+			frame = &resized
+		}
+		m := c.formatFunc(frame)
 		t := core.NewTuple(m)
 		if err := w.Write(ctx, t); err != nil {
 			return err
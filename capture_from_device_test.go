@@ -84,6 +84,21 @@ func TestGetDeviceSourceCreator(t *testing.T) {
 			})
 		})
 
+		Convey("When create source with jpeg format", func() {
+			params := data.Map{
+				"device_id":    data.Int(0),
+				"format":       data.String("jpeg"),
+				"jpeg_quality": data.Int(80),
+			}
+			Convey("Then creator should initialize capture source", func() {
+				s, err := sc.createCaptureFromDevice(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				capture, ok := s.(*captureFromDevice)
+				So(ok, ShouldBeTrue)
+				So(capture.formatFunc, ShouldNotBeNil)
+			})
+		})
+
 		Convey("When create source with invalid device ID", func() {
 			params := data.Map{
 				"device_id": data.String("a"),
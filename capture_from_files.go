@@ -0,0 +1,290 @@
+package opencv
+
+import (
+	"bufio"
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/bql"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FromFilesCreator is a creator of a capture from image files.
+type FromFilesCreator struct{}
+
+var (
+	patternPath  = data.MustCompilePath("pattern")
+	manifestPath = data.MustCompilePath("manifest")
+	loopPath     = data.MustCompilePath("loop")
+)
+
+// CreateSource creates a frame generator which reads still image files
+// decoded by OpenCV (`cv::imdecode`), e.g. to replay images for regression
+// tests of detection pipelines.
+//
+// WITH parameters.
+//
+// pattern: A glob pattern of image files (e.g. "/data/images/*.jpg"), the
+// syntax is the same as Go's filepath.Match. Either pattern or manifest is
+// required.
+//
+// manifest: A path of a text file which lists paths of image files line by
+// line. Relative paths are resolved from the directory of the manifest, and
+// empty lines and lines starting with "#" are ignored.
+//
+// format: Output format style, "cvmat" or "jpeg", default is "cvmat".
+//
+// jpeg_quality: The quality of JPEG encoding from 0 to 100, default is 95.
+// This parameter is used only when format is "jpeg".
+//
+// resize_width: Width of output frames. If only one of resize_width and
+// resize_height is set then the other is decided with keeping the aspect
+// ratio. Frames are resized before they are converted to tuples.
+//
+// resize_height: Height of output frames, see resize_width.
+//
+// scale: Scale factor of output frames (e.g. 0.5), which cannot be set with
+// resize_width or resize_height.
+//
+// loop: If set `true` then files are read repeatedly from the first file
+// after the last file. Default value is false.
+//
+// fps: The number of frames emitted per second (e.g. 0.5 emits a frame every
+// two seconds). If set empty or "0" then frames are emitted as fast as
+// possible.
+//
+// rewindable: If set `true` then user can use `REWIND SOURCE` query.
+func (c *FromFilesCreator) CreateSource(ctx *core.Context,
+	ioParams *bql.IOParams, params data.Map) (core.Source, error) {
+
+	cs, err := c.createCaptureFromFiles(ctx, ioParams, params)
+	if err != nil {
+		return nil, err
+	}
+
+	rewindFlag := false
+	if rf, err := params.Get(rewindablePath); err == nil {
+		if rewindFlag, err = data.AsBool(rf); err != nil {
+			return nil, err
+		}
+	}
+	if rewindFlag {
+		return core.NewRewindableSource(cs), nil
+	}
+	return core.ImplementSourceStop(cs), nil
+}
+
+func (c *FromFilesCreator) createCaptureFromFiles(ctx *core.Context,
+	ioParams *bql.IOParams, params data.Map) (core.Source, error) {
+
+	var files []string
+	pattern, patternErr := params.Get(patternPath)
+	manifest, manifestErr := params.Get(manifestPath)
+	switch {
+	case patternErr == nil && manifestErr == nil:
+		return nil, fmt.Errorf("pattern and manifest cannot be set together")
+	case patternErr == nil:
+		p, err := data.AsString(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if files, err = filepath.Glob(p); err != nil {
+			return nil, err
+		}
+	case manifestErr == nil:
+		p, err := data.AsString(manifest)
+		if err != nil {
+			return nil, err
+		}
+		if files, err = readManifest(p); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("capture source needs pattern or manifest")
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no image file is found")
+	}
+	sort.Strings(files)
+
+	formatFunc, err := newFormatFunc(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resizer, err := newFrameResizer(params)
+	if err != nil {
+		return nil, err
+	}
+
+	loop := false
+	if l, err := params.Get(loopPath); err == nil {
+		if loop, err = data.AsBool(l); err != nil {
+			return nil, err
+		}
+	}
+
+	fps := 0.0
+	if f, err := params.Get(fpsPath); err == nil {
+		if fps, err = data.ToFloat(f); err != nil {
+			return nil, err
+		}
+		if fps < 0 {
+			return nil, fmt.Errorf("fps must not be negative: %v", fps)
+		}
+	}
+
+	cs := &captureFromFiles{
+		files:      files,
+		loop:       loop,
+		fps:        fps,
+		formatFunc: formatFunc,
+		resizer:    resizer,
+		stop:       make(chan struct{}),
+	}
+	return cs, nil
+}
+
+// readManifest returns paths of files listed in the manifest. Relative paths
+// are resolved from the directory of the manifest.
+func readManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(path)
+	files := []string{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(dir, line)
+		}
+		files = append(files, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+type captureFromFiles struct {
+	files      []string
+	loop       bool
+	fps        float64
+	formatFunc func(m *bridge.MatVec3b) data.Map
+	resizer    *frameResizer
+
+	// stop is closed by Stop to interrupt the wait for the next frame.
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// GenerateStream streams images read from files in sorted order. When all
+// files are read, the stream ends unless loop is `true`. Files which cannot
+// be read or decoded are skipped with an error log.
+//
+// Output
+//
+// format: The frame's format style, ex) "cvmat", "jpeg",...
+//
+// mode: The frame's color mode, "BGR" for "cvmat". This field is not set for
+// compressed formats (e.g. "jpeg").
+//
+// width: The frame's width.
+//
+// height: The frame's height.
+//
+// image: The binary data of frame image.
+//
+// file_name: The path of the image file.
+func (c *captureFromFiles) GenerateStream(ctx *core.Context, w core.Writer) error {
+	resized := bridge.NewMatVec3b()
+	defer resized.Delete()
+
+	var interval time.Duration
+	if c.fps > 0 {
+		interval = time.Duration(float64(time.Second) / c.fps)
+	}
+	next := time.Now()
+
+	cnt := 0
+	ctx.Log().Infof("start reading %d image files", len(c.files))
+	for {
+		read := 0
+		for _, file := range c.files {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				ctx.Log().Errorf("cannot read the image file: %v", err)
+				continue
+			}
+			buf := bridge.DecodeToMatVec3b(b)
+			if buf.Empty() {
+				buf.Delete()
+				ctx.Log().Errorf("cannot decode the image file: %v", file)
+				continue
+			}
+
+			frame := &buf
+			if c.resizer != nil {
+				c.resizer.resize(buf, resized)
+				frame = &resized
+			}
+			m := c.formatFunc(frame)
+			buf.Delete()
+			m["file_name"] = data.String(file)
+
+			if interval > 0 {
+				// the time spent on decoding is included in the interval, and
+				// frames are not emitted in a burst after a delay (e.g. pause)
+				if now := time.Now(); next.After(now) {
+					select {
+					case <-c.stop:
+						ctx.Log().Infof("total read frames count is %d",
+							cnt+read)
+						return nil
+					case <-time.After(next.Sub(now)):
+					}
+				} else {
+					next = now
+				}
+				next = next.Add(interval)
+			}
+			t := core.NewTuple(m)
+			if err := w.Write(ctx, t); err != nil {
+				return err
+			}
+			read++
+		}
+		cnt += read
+		if !c.loop {
+			break
+		}
+		if read == 0 {
+			return fmt.Errorf("no image file can be read")
+		}
+	}
+	ctx.Log().Infof("total read frames count is %d", cnt)
+	return nil
+}
+
+// Stop interrupts the wait for the next frame, and GenerateStream returns
+// without emitting it.
+func (c *captureFromFiles) Stop(ctx *core.Context) error {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+	return nil
+}
@@ -0,0 +1,206 @@
+package opencv
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/sensorbee.v0/bql"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestImages writes PNG images of which widths are 1, 2, ... to the
+// directory with the names.
+func writeTestImages(dir string, names ...string) {
+	for i, n := range names {
+		f, err := os.Create(filepath.Join(dir, n))
+		So(err, ShouldBeNil)
+		So(png.Encode(f, image.NewGray(image.Rect(0, 0, i+1, 1))), ShouldBeNil)
+		So(f.Close(), ShouldBeNil)
+	}
+}
+
+type tupleCollector struct {
+	tuples []*core.Tuple
+}
+
+func (w *tupleCollector) Write(ctx *core.Context, t *core.Tuple) error {
+	w.tuples = append(w.tuples, t)
+	return nil
+}
+
+func TestGetFilesSourceCreator(t *testing.T) {
+	cc := &core.ContextConfig{}
+	ctx := core.NewContext(cc)
+	ioParams := &bql.IOParams{}
+	Convey("Given a directory which has image files", t, func() {
+		dir, err := ioutil.TempDir("", "capture_from_files")
+		So(err, ShouldBeNil)
+		Reset(func() {
+			os.RemoveAll(dir)
+		})
+		writeTestImages(dir, "b.png", "a.png")
+		sc := FromFilesCreator{}
+
+		Convey("When create source with a glob pattern", func() {
+			params := data.Map{
+				"pattern": data.String(filepath.Join(dir, "*.png")),
+			}
+			s, err := sc.createCaptureFromFiles(ctx, ioParams, params)
+			So(err, ShouldBeNil)
+			Convey("Then capture should have sorted files and default values", func() {
+				capture, ok := s.(*captureFromFiles)
+				So(ok, ShouldBeTrue)
+				So(capture.files, ShouldResemble, []string{
+					filepath.Join(dir, "a.png"),
+					filepath.Join(dir, "b.png"),
+				})
+				So(capture.loop, ShouldBeFalse)
+				So(capture.fps, ShouldEqual, 0)
+				So(capture.resizer, ShouldBeNil)
+			})
+			Convey("And generate stream", func() {
+				w := &tupleCollector{}
+				So(s.GenerateStream(ctx, w), ShouldBeNil)
+				Convey("Then images should be emitted in sorted order", func() {
+					So(len(w.tuples), ShouldEqual, 2)
+					for i, n := range []string{"a.png", "b.png"} {
+						m := w.tuples[i].Data
+						So(m["file_name"], ShouldEqual,
+							data.String(filepath.Join(dir, n)))
+						So(m["format"], ShouldEqual, data.String("cvmat"))
+					}
+					So(w.tuples[0].Data["width"], ShouldEqual, data.Int(2))
+					So(w.tuples[1].Data["width"], ShouldEqual, data.Int(1))
+				})
+			})
+		})
+
+		Convey("When create source with a very low fps", func() {
+			params := data.Map{
+				"pattern": data.String(filepath.Join(dir, "*.png")),
+				"fps":     data.Float(0.001),
+			}
+			s, err := sc.createCaptureFromFiles(ctx, ioParams, params)
+			So(err, ShouldBeNil)
+			Convey("And stop it while generating stream", func() {
+				w := &tupleCollector{}
+				done := make(chan error, 1)
+				go func() {
+					done <- s.GenerateStream(ctx, w)
+				}()
+				So(s.Stop(ctx), ShouldBeNil)
+				Convey("Then the wait for the next frame should be interrupted", func() {
+					var err error
+					select {
+					case err = <-done:
+					case <-time.After(10 * time.Second):
+						err = fmt.Errorf("GenerateStream does not return")
+					}
+					So(err, ShouldBeNil)
+					So(len(w.tuples), ShouldBeLessThan, 2)
+				})
+			})
+		})
+
+		Convey("When create source with a manifest", func() {
+			manifest := filepath.Join(dir, "manifest.txt")
+			So(ioutil.WriteFile(manifest, []byte("# images\nb.png\n\n"+
+				filepath.Join(dir, "a.png")+"\n"), 0644), ShouldBeNil)
+			params := data.Map{
+				"manifest": data.String(manifest),
+				"loop":     data.True,
+				"fps":      data.Float(2.5),
+			}
+			Convey("Then capture should have listed files and parameters", func() {
+				s, err := sc.createCaptureFromFiles(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				capture, ok := s.(*captureFromFiles)
+				So(ok, ShouldBeTrue)
+				So(capture.files, ShouldResemble, []string{
+					filepath.Join(dir, "a.png"),
+					filepath.Join(dir, "b.png"),
+				})
+				So(capture.loop, ShouldBeTrue)
+				So(capture.fps, ShouldEqual, 2.5)
+			})
+		})
+
+		Convey("When create source with a pattern which matches no file", func() {
+			params := data.Map{
+				"pattern": data.String(filepath.Join(dir, "*.jpg")),
+			}
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.createCaptureFromFiles(ctx, ioParams, params)
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When create source with both pattern and manifest", func() {
+			params := data.Map{
+				"pattern":  data.String(filepath.Join(dir, "*.png")),
+				"manifest": data.String(filepath.Join(dir, "manifest.txt")),
+			}
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.createCaptureFromFiles(ctx, ioParams, params)
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When create source with neither pattern nor manifest", func() {
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.createCaptureFromFiles(ctx, ioParams, data.Map{})
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When create source with invalid option parameters", func() {
+			params := data.Map{
+				"pattern": data.String(filepath.Join(dir, "*.png")),
+			}
+			testMap := data.Map{
+				"manifest": data.String(filepath.Join(dir, "not_exist.txt")),
+				"pattern":  data.String("[-]"),
+				"format":   data.True,
+				"loop":     data.String("True"),
+				"fps":      data.Float(-1),
+			}
+			for k, v := range testMap {
+				k, v := k, v
+				msg := fmt.Sprintf("with %v error", k)
+				Convey("Then creator should occur a parse error on option parameters"+msg, func() {
+					if k == "manifest" {
+						delete(params, "pattern")
+					}
+					params[k] = v
+					s, err := sc.createCaptureFromFiles(ctx, ioParams, params)
+					So(err, ShouldNotBeNil)
+					So(s, ShouldBeNil)
+				})
+			}
+		})
+
+		Convey("When create source with rewindable", func() {
+			params := data.Map{
+				"pattern":    data.String(filepath.Join(dir, "*.png")),
+				"rewindable": data.True,
+			}
+			Convey("Then rewindable capture should be created", func() {
+				s, err := sc.CreateSource(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				So(s, ShouldNotBeNil)
+				_, ok := s.(core.RewindableSource)
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
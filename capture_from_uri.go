@@ -6,6 +6,9 @@ import (
 	"gopkg.in/sensorbee/sensorbee.v0/bql"
 	"gopkg.in/sensorbee/sensorbee.v0/core"
 	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"math"
+	"sync"
+	"time"
 )
 
 // FromURICreator is a creator of a capture from URI.
@@ -18,6 +21,9 @@ var (
 	nextFrameErrorPath = data.MustCompilePath("next_frame_error")
 	rewindPath         = data.MustCompilePath("rewind")
 	rewindablePath     = data.MustCompilePath("rewindable")
+	startTimePath      = data.MustCompilePath("start_time")
+	realtimePath       = data.MustCompilePath("realtime")
+	speedPath          = data.MustCompilePath("speed")
 )
 
 // CreateSource creates a frame generator using OpenCV video capture.
@@ -27,7 +33,19 @@ var (
 //
 // uri: [required] A capture data's URI (e.g. /data/test.avi).
 //
-// format: Output format style, default is "cvmat".
+// format: Output format style, "cvmat" or "jpeg", default is "cvmat".
+//
+// jpeg_quality: The quality of JPEG encoding from 0 to 100, default is 95.
+// This parameter is used only when format is "jpeg".
+//
+// resize_width: Width of output frames. If only one of resize_width and
+// resize_height is set then the other is decided with keeping the aspect
+// ratio. Frames are resized before they are converted to tuples.
+//
+// resize_height: Height of output frames, see resize_width.
+//
+// scale: Scale factor of output frames (e.g. 0.5), which cannot be set with
+// resize_width or resize_height.
 //
 // frame_skip: The number of frame skip, if set empty or "0" then read all
 // frames. FPS is depended on the URI's file (or device).
@@ -37,6 +55,18 @@ var (
 // value is true.
 //
 // rewindable: If set `true` then user can use `REWIND SOURCE` query.
+//
+// start_time: The time when the video was recorded, as a timestamp or an
+// RFC3339 string (e.g. "2017-01-02T15:04:05+09:00"). If set then timestamps
+// of tuples are the time plus the media time of frames, which makes window
+// queries over recorded videos behave like live streams.
+//
+// realtime: If set `true` then frames are emitted at the pace of the media
+// time of the video (i.e. its FPS) instead of as fast as they are decoded,
+// which reproduces the load of live streams. Default value is false.
+//
+// speed: The playback speed multiplier used when realtime is `true`, e.g. 2.0
+// is fast-forward and 0.5 is slow motion. Default value is 1.0.
 func (c *FromURICreator) CreateSource(ctx *core.Context,
 	ioParams *bql.IOParams, params data.Map) (core.Source, error) {
 
@@ -76,11 +106,14 @@ func (c *FromURICreator) createCaptureFromURI(ctx *core.Context,
 		return nil, err
 	}
 
-	format := "cvmat"
-	if fm, err := params.Get(formatPath); err == nil {
-		if format, err = data.AsString(fm); err != nil {
-			return nil, err
-		}
+	formatFunc, err := newFormatFunc(params)
+	if err != nil {
+		return nil, err
+	}
+
+	resizer, err := newFrameResizer(params)
+	if err != nil {
+		return nil, err
 	}
 
 	fs, err := params.Get(frameSkipPath)
@@ -101,15 +134,40 @@ func (c *FromURICreator) createCaptureFromURI(ctx *core.Context,
 		return nil, err
 	}
 
+	var startTime time.Time
+	if st, err := params.Get(startTimePath); err == nil {
+		if startTime, err = data.ToTimestamp(st); err != nil {
+			return nil, err
+		}
+	}
+
+	realtime := false
+	if rt, err := params.Get(realtimePath); err == nil {
+		if realtime, err = data.AsBool(rt); err != nil {
+			return nil, err
+		}
+	}
+
+	speed := 1.0
+	if sp, err := params.Get(speedPath); err == nil {
+		if speed, err = data.ToFloat(sp); err != nil {
+			return nil, err
+		}
+		if speed <= 0 {
+			return nil, fmt.Errorf("speed must be greater than 0: %v", speed)
+		}
+	}
+
 	cs := &captureFromURI{
 		uri:        uriStr,
 		frameSkip:  frameSkip,
 		endErrFlag: endErr,
-	}
-	if format == "cvmat" {
-		cs.foramtFunc = toRawMap
-	} else {
-		return nil, fmt.Errorf("'%v' format is not supported", format)
+		foramtFunc: formatFunc,
+		resizer:    resizer,
+		startTime:  startTime,
+		realtime:   realtime,
+		speed:      speed,
+		stop:       make(chan struct{}),
 	}
 	return cs, nil
 }
@@ -119,6 +177,79 @@ type captureFromURI struct {
 	frameSkip  int64
 	endErrFlag bool
 	foramtFunc func(m *bridge.MatVec3b) data.Map
+	resizer    *frameResizer
+	startTime  time.Time
+	realtime   bool
+	speed      float64
+
+	// stop is closed by Stop to interrupt the wait of realtime playback.
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// mediaTime returns the time of the frame from the beginning of the video.
+// When the backend does not support the position in milliseconds, the time is
+// calculated from the frame index and FPS. Returns 0 when neither of them is
+// available.
+func mediaTime(posMsec float64, frameIndex int64, fps float64) time.Duration {
+	if posMsec > 0 && !math.IsInf(posMsec, 0) {
+		return time.Duration(posMsec * float64(time.Millisecond))
+	}
+	if fps > 0 && !math.IsInf(fps, 0) {
+		return time.Duration(float64(frameIndex) / fps * float64(time.Second))
+	}
+	return 0
+}
+
+// framePacer paces emission of frames according to their media time. The
+// elapsed time is measured as a difference from the time of the first frame
+// with the monotonic clock, and is not affected by the wall clock adjustment.
+type framePacer struct {
+	speed   float64
+	started bool
+	start   time.Duration
+	base    time.Duration
+
+	now func() time.Duration
+	// sleep sleeps for the duration, and returns false when it's interrupted.
+	sleep func(time.Duration) bool
+}
+
+// newFramePacer returns framePacer of which sleep is interrupted when the
+// stop channel is closed.
+func newFramePacer(speed float64, stop <-chan struct{}) *framePacer {
+	return &framePacer{
+		speed: speed,
+		now:   bridge.MonotonicNow,
+		sleep: func(d time.Duration) bool {
+			select {
+			case <-stop:
+				return false
+			case <-time.After(d):
+				return true
+			}
+		},
+	}
+}
+
+// wait sleeps until the time to emit the frame of the media time. When the
+// frame is already late (e.g. the source was paused or decoding is slower
+// than the speed), it returns immediately and following frames are paced
+// from the frame so that they are not emitted in a burst. Returns false when
+// the sleep is interrupted by stop.
+func (p *framePacer) wait(media time.Duration) bool {
+	if !p.started {
+		p.started = true
+		p.start = p.now()
+		p.base = media
+		return true
+	}
+	target := time.Duration(float64(media-p.base) / p.speed)
+	if d := target - (p.now() - p.start); d > 0 {
+		return p.sleep(d)
+	}
+	p.start -= d
+	return true
 }
 
 // GenerateStream streams video capture data. OpenCV video capture read frames
@@ -129,7 +260,8 @@ type captureFromURI struct {
 //
 // format: The frame's format style, ex) "cvmat", "jpeg",...
 //
-// mode: The frame's format mode, ex) "BGR", "RGBA",...
+// mode: The frame's color mode, "BGR" for "cvmat". This field is not set for
+// compressed formats (e.g. "jpeg").
 //
 // width: The frame's width.
 //
@@ -137,9 +269,18 @@ type captureFromURI struct {
 //
 // image: The binary data of frame image.
 //
+// frame_index: The index of the frame in the video starting from 0, which
+// counts skipped frames.
+//
+// pos_msec: The position of the frame in milliseconds
+// (`CAP_PROP_POS_MSEC`), which is 0 when the backend does not support it.
+//
+// source_fps: FPS of the video (`CAP_PROP_FPS`), which is 0 when the backend
+// does not support it.
+//
 // When a capture source is a file-style (e.g. AVI file), tuples' timestamp is
 // NOT correspond with the file created time. The timestamp value is the time
-// of this source capturing a new frame.
+// of this source capturing a new frame unless "start_time" is set.
 // And when complete to read the file's all frames, video capture cannot read a
 // new frame. If the key "next_frame_error" set `false` then a no new frame
 // error will not be occurred, User can also count the number of total frame to
@@ -153,8 +294,24 @@ func (c *captureFromURI) GenerateStream(ctx *core.Context, w core.Writer) error
 
 	buf := bridge.NewMatVec3b()
 	defer buf.Delete()
+	resized := bridge.NewMatVec3b()
+	defer resized.Delete()
+	fps := vcap.Get(bridge.CvCapPropFps)
+	if math.IsNaN(fps) {
+		fps = 0
+	}
+
+	var pacer *framePacer
+	if c.realtime {
+		if fps <= 0 {
+			ctx.Log().Warnf("FPS of the video is not available, realtime "+
+				"playback depends on the position of frames: %v", c.uri)
+		}
+		pacer = newFramePacer(c.speed, c.stop)
+	}
 
 	cnt := 0
+	frameIndex := int64(0)
 	ctx.Log().Infof("start reading video stream of file: %v", c.uri)
 	for {
 		cnt++
@@ -165,12 +322,33 @@ func (c *captureFromURI) GenerateStream(ctx *core.Context, w core.Writer) error
 			}
 			break
 		}
+		posMsec := vcap.Get(bridge.CvCapPropPosMsec)
+		if math.IsNaN(posMsec) {
+			posMsec = 0
+		}
+		index := frameIndex
+		frameIndex += 1 + c.frameSkip
 		if c.frameSkip > 0 {
 			vcap.Grab(int(c.frameSkip))
 		}
 
-		m := c.foramtFunc(&buf)
+		frame := &buf
+		if c.resizer != nil && !buf.Empty() {
+			c.resizer.resize(buf, resized)
+			frame = &resized
+		}
+		m := c.foramtFunc(frame)
+		m["frame_index"] = data.Int(index)
+		m["pos_msec"] = data.Float(posMsec)
+		m["source_fps"] = data.Float(fps)
+		if pacer != nil && !pacer.wait(mediaTime(posMsec, index, fps)) {
+			ctx.Log().Infof("total read frames count is %d", cnt-1)
+			return nil
+		}
 		t := core.NewTuple(m)
+		if !c.startTime.IsZero() {
+			t.Timestamp = c.startTime.Add(mediaTime(posMsec, index, fps))
+		}
 		if err := w.Write(ctx, t); err != nil {
 			return err
 		}
@@ -178,6 +356,11 @@ func (c *captureFromURI) GenerateStream(ctx *core.Context, w core.Writer) error
 	return nil
 }
 
+// Stop interrupts the wait of realtime playback, and GenerateStream returns
+// without emitting the frame.
 func (c *captureFromURI) Stop(ctx *core.Context) error {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
 	return nil
 }
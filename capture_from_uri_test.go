@@ -6,7 +6,9 @@ import (
 	"gopkg.in/sensorbee/sensorbee.v0/bql"
 	"gopkg.in/sensorbee/sensorbee.v0/core"
 	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"math"
 	"testing"
+	"time"
 )
 
 func TestGenerateStreamURIError(t *testing.T) {
@@ -102,6 +104,7 @@ func TestGetURISourceCreator(t *testing.T) {
 				So(capture.uri, ShouldEqual, "/data/file.avi")
 				So(capture.frameSkip, ShouldEqual, 0)
 				So(capture.endErrFlag, ShouldBeTrue)
+				So(capture.resizer, ShouldBeNil)
 			})
 		})
 
@@ -124,6 +127,9 @@ func TestGetURISourceCreator(t *testing.T) {
 				"format":           data.True,
 				"frame_skip":       data.String("@"),
 				"next_frame_error": data.String("True"),
+				"start_time":       data.String("today"),
+				"realtime":         data.String("True"),
+				"speed":            data.Float(0),
 			}
 			for k, v := range testMap {
 				v := v
@@ -137,6 +143,92 @@ func TestGetURISourceCreator(t *testing.T) {
 			}
 		})
 
+		Convey("When create source with jpeg format", func() {
+			params := data.Map{
+				"uri":          data.String("/data/file.avi"),
+				"format":       data.String("jpeg"),
+				"jpeg_quality": data.Int(80),
+			}
+			Convey("Then creator should initialize capture source", func() {
+				s, err := sc.createCaptureFromURI(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				capture, ok := s.(*captureFromURI)
+				So(ok, ShouldBeTrue)
+				So(capture.foramtFunc, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When create source with resize parameters", func() {
+			params := data.Map{
+				"uri":          data.String("/data/file.avi"),
+				"resize_width": data.Int(640),
+			}
+			Convey("Then capture should have a resizer", func() {
+				s, err := sc.createCaptureFromURI(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				capture, ok := s.(*captureFromURI)
+				So(ok, ShouldBeTrue)
+				So(capture.resizer, ShouldResemble, &frameResizer{width: 640})
+			})
+		})
+
+		Convey("When create source with start_time", func() {
+			params := data.Map{
+				"uri":        data.String("/data/file.avi"),
+				"start_time": data.String("2017-01-02T15:04:05Z"),
+			}
+			Convey("Then capture should have the start time", func() {
+				s, err := sc.createCaptureFromURI(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				capture, ok := s.(*captureFromURI)
+				So(ok, ShouldBeTrue)
+				So(capture.startTime.Equal(
+					time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)), ShouldBeTrue)
+			})
+		})
+
+		Convey("When create source with realtime and speed", func() {
+			params := data.Map{
+				"uri":      data.String("/data/file.avi"),
+				"realtime": data.True,
+				"speed":    data.Float(2),
+			}
+			Convey("Then capture should pace frames with the speed", func() {
+				s, err := sc.createCaptureFromURI(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				capture, ok := s.(*captureFromURI)
+				So(ok, ShouldBeTrue)
+				So(capture.realtime, ShouldBeTrue)
+				So(capture.speed, ShouldEqual, 2)
+			})
+		})
+
+		Convey("When create source with both resize_width and scale", func() {
+			params := data.Map{
+				"uri":          data.String("/data/file.avi"),
+				"resize_width": data.Int(640),
+				"scale":        data.Float(0.5),
+			}
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.createCaptureFromURI(ctx, ioParams, params)
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When create source with jpeg format and invalid quality", func() {
+			params := data.Map{
+				"uri":          data.String("/data/file.avi"),
+				"format":       data.String("jpeg"),
+				"jpeg_quality": data.Int(101),
+			}
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.createCaptureFromURI(ctx, ioParams, params)
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
 		Convey("When create source with only uri and rewindable", func() {
 			params := data.Map{
 				"uri":        data.String("/data/file.avi"),
@@ -168,3 +260,76 @@ func TestGetURISourceCreator(t *testing.T) {
 		})
 	})
 }
+
+func TestMediaTime(t *testing.T) {
+	Convey("Given the position of a frame in milliseconds", t, func() {
+		Convey("When calculate the media time", func() {
+			d := mediaTime(1500, 45, 30)
+			Convey("Then the time should be the position", func() {
+				So(d, ShouldEqual, 1500*time.Millisecond)
+			})
+		})
+	})
+
+	Convey("Given the position which is not supported", t, func() {
+		Convey("When calculate the media time with FPS", func() {
+			d := mediaTime(0, 45, 30)
+			Convey("Then the time should be calculated from the frame index", func() {
+				So(d, ShouldEqual, 1500*time.Millisecond)
+			})
+		})
+		Convey("When calculate the media time without FPS", func() {
+			d := mediaTime(0, 45, math.Inf(1))
+			Convey("Then the time should be 0", func() {
+				So(d, ShouldEqual, 0)
+			})
+		})
+	})
+}
+
+func TestFramePacer(t *testing.T) {
+	Convey("Given a frame pacer with a fake clock", t, func() {
+		now := 100 * time.Second
+		slept := []time.Duration{}
+		stop := make(chan struct{})
+		p := newFramePacer(2, stop)
+		p.now = func() time.Duration {
+			return now
+		}
+		p.sleep = func(d time.Duration) bool {
+			slept = append(slept, d)
+			now += d
+			return true
+		}
+		Convey("When frames are decoded in no time", func() {
+			for _, m := range []time.Duration{10, 11, 12} {
+				So(p.wait(m*time.Second), ShouldBeTrue)
+			}
+			Convey("Then it should sleep according to the speed", func() {
+				So(slept, ShouldResemble, []time.Duration{
+					500 * time.Millisecond, 500 * time.Millisecond,
+				})
+			})
+		})
+		Convey("When a frame is late", func() {
+			p.wait(0)
+			now += 3 * time.Second
+			p.wait(time.Second)
+			p.wait(2 * time.Second)
+			Convey("Then following frames should not be emitted in a burst", func() {
+				So(slept, ShouldResemble, []time.Duration{500 * time.Millisecond})
+			})
+		})
+		Convey("When it is stopped before the time of a frame", func() {
+			p := newFramePacer(2, stop)
+			p.now = func() time.Duration {
+				return now
+			}
+			So(p.wait(0), ShouldBeTrue)
+			close(stop)
+			Convey("Then it should return without waiting for the frame", func() {
+				So(p.wait(time.Hour), ShouldBeFalse)
+			})
+		})
+	})
+}
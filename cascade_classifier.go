@@ -8,15 +8,63 @@ import (
 )
 
 var (
-	configFilePath = data.MustCompilePath("file")
-	xPath          = data.MustCompilePath("x")
-	yPath          = data.MustCompilePath("y")
+	configFilePath   = data.MustCompilePath("file")
+	xPath            = data.MustCompilePath("x")
+	yPath            = data.MustCompilePath("y")
+	scaleFactorPath  = data.MustCompilePath("scale_factor")
+	minNeighborsPath = data.MustCompilePath("min_neighbors")
+	flagsPath        = data.MustCompilePath("flags")
+	minSizePath      = data.MustCompilePath("min_size")
+	maxSizePath      = data.MustCompilePath("max_size")
+	grayscalePath    = data.MustCompilePath("grayscale")
+	equalizePath     = data.MustCompilePath("equalize")
+	clipLimitPath    = data.MustCompilePath("clahe_clip_limit")
+	tileSizePath     = data.MustCompilePath("clahe_tile_size")
+	downscalePath    = data.MustCompilePath("downscale")
 )
 
 // NewCascadeClassifier returns cascadeClassifier state.
 //
 // file: cascade configuration file path for detection.
 // e.g. "haarcascade_frontalface_default.xml".
+//
+// scale_factor: How much the image size is reduced at each image scale,
+// default is 1.1. The value is required to be greater than 1.
+//
+// min_neighbors: How many neighbors each candidate rectangle should have to
+// retain it, default is 3.
+//
+// flags: OpenCV's flags of old cascade format (e.g. 2 = CV_HAAR_SCALE_IMAGE),
+// default is 0.
+//
+// min_size: Minimum possible object size as a map which has "width" and
+// "height" (e.g. {"width":30, "height":30}), default is no limit.
+//
+// max_size: Maximum possible object size as the same structure of min_size,
+// default is no limit.
+//
+// grayscale: If set `true` then the image is converted to grayscale before
+// detection, default is false.
+//
+// equalize: Histogram equalization before detection, "none", "hist"
+// (`cv::equalizeHist`) or "clahe" (`cv::CLAHE`), default is "none". The image
+// is converted to grayscale when equalization is enabled.
+//
+// clahe_clip_limit: Threshold for contrast limiting of CLAHE, default is 2.
+//
+// clahe_tile_size: Size of grid for CLAHE, default is 8.
+//
+// downscale: A factor to downscale the image before detection, e.g. 2 makes
+// the image half size, default is 1 (no scaling). Detected rectangles,
+// min_size and max_size are on the original image's coordinates. The image is
+// not downscaled when its downscaled size would be less than 1 pixel.
+//
+// roi: A region of interest as a map which has "x", "y", "width" and
+// "height", the detection runs only on the region, default is the whole
+// image. Detected rectangles are on the original image's coordinates.
+//
+// These detection parameters are used as default values of
+// opencv_detect_multi_scale.
 func NewCascadeClassifier(ctx *core.Context, params data.Map) (core.SharedState,
 	error) {
 	var filePath string
@@ -26,6 +74,12 @@ func NewCascadeClassifier(ctx *core.Context, params data.Map) (core.SharedState,
 		return nil, err
 	}
 
+	detectParams, err := parseDetectMultiScaleParams(
+		bridge.DefaultDetectMultiScaleParams(), params)
+	if err != nil {
+		return nil, err
+	}
+
 	cc := bridge.NewCascadeClassifier()
 	if !cc.Load(filePath) {
 		return nil, fmt.Errorf("cannot load the file '%v'", filePath)
@@ -33,11 +87,150 @@ func NewCascadeClassifier(ctx *core.Context, params data.Map) (core.SharedState,
 
 	return &cascadeClassifier{
 		classifier: cc,
+		params:     detectParams,
 	}, nil
 }
 
 type cascadeClassifier struct {
 	classifier bridge.CascadeClassifier
+	params     bridge.DetectMultiScaleParams
+}
+
+// parseDetectMultiScaleParams returns detection parameters which are
+// overwritten by values in the map.
+func parseDetectMultiScaleParams(params bridge.DetectMultiScaleParams,
+	m data.Map) (bridge.DetectMultiScaleParams, error) {
+	if sf, err := m.Get(scaleFactorPath); err == nil {
+		if params.ScaleFactor, err = data.ToFloat(sf); err != nil {
+			return params, err
+		}
+		if params.ScaleFactor <= 1 {
+			return params, fmt.Errorf("scale_factor must be greater than 1: %v",
+				params.ScaleFactor)
+		}
+	}
+
+	if mn, err := m.Get(minNeighborsPath); err == nil {
+		minNeighbors, err := data.ToInt(mn)
+		if err != nil {
+			return params, err
+		}
+		if minNeighbors < 0 {
+			return params, fmt.Errorf("min_neighbors must not be negative: %v",
+				minNeighbors)
+		}
+		params.MinNeighbors = int(minNeighbors)
+	}
+
+	if f, err := m.Get(flagsPath); err == nil {
+		flags, err := data.ToInt(f)
+		if err != nil {
+			return params, err
+		}
+		params.Flags = int(flags)
+	}
+
+	if s, err := m.Get(minSizePath); err == nil {
+		if params.MinSize, err = convertToBridgeSize(s); err != nil {
+			return params, err
+		}
+	}
+
+	if s, err := m.Get(maxSizePath); err == nil {
+		if params.MaxSize, err = convertToBridgeSize(s); err != nil {
+			return params, err
+		}
+	}
+
+	if g, err := m.Get(grayscalePath); err == nil {
+		if params.Grayscale, err = data.AsBool(g); err != nil {
+			return params, err
+		}
+	}
+
+	if e, err := m.Get(equalizePath); err == nil {
+		equalize, err := data.AsString(e)
+		if err != nil {
+			return params, err
+		}
+		switch equalize {
+		case "none":
+			params.Equalization = bridge.EqualizationNone
+		case "hist":
+			params.Equalization = bridge.EqualizationHist
+		case "clahe":
+			params.Equalization = bridge.EqualizationCLAHE
+		default:
+			return params, fmt.Errorf("'%v' equalization is not supported",
+				equalize)
+		}
+	}
+
+	if cl, err := m.Get(clipLimitPath); err == nil {
+		if params.CLAHEClipLimit, err = data.ToFloat(cl); err != nil {
+			return params, err
+		}
+		if params.CLAHEClipLimit <= 0 {
+			return params, fmt.Errorf("clahe_clip_limit must be positive: %v",
+				params.CLAHEClipLimit)
+		}
+	}
+
+	if ts, err := m.Get(tileSizePath); err == nil {
+		tileSize, err := data.ToInt(ts)
+		if err != nil {
+			return params, err
+		}
+		if tileSize <= 0 {
+			return params, fmt.Errorf("clahe_tile_size must be positive: %v",
+				tileSize)
+		}
+		params.CLAHETileSize = int(tileSize)
+	}
+
+	if ds, err := m.Get(downscalePath); err == nil {
+		if params.Downscale, err = data.ToFloat(ds); err != nil {
+			return params, err
+		}
+		if params.Downscale < 1 {
+			return params, fmt.Errorf("downscale must be 1 or greater: %v",
+				params.Downscale)
+		}
+	}
+
+	if r, err := m.Get(roiPath); err == nil {
+		if params.ROI, err = convertToBridgeRect(r); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+func convertToBridgeSize(v data.Value) (bridge.Size, error) {
+	m, err := data.AsMap(v)
+	if err != nil {
+		return bridge.Size{}, err
+	}
+	var width int64
+	if wv, err := m.Get(widthPath); err != nil {
+		return bridge.Size{}, err
+	} else if width, err = data.ToInt(wv); err != nil {
+		return bridge.Size{}, err
+	}
+	var height int64
+	if hv, err := m.Get(heightPath); err != nil {
+		return bridge.Size{}, err
+	} else if height, err = data.ToInt(hv); err != nil {
+		return bridge.Size{}, err
+	}
+	if width < 0 || height < 0 {
+		return bridge.Size{}, fmt.Errorf("size must not be negative: %vx%v",
+			width, height)
+	}
+	return bridge.Size{
+		Width:  int(width),
+		Height: int(height),
+	}, nil
 }
 
 func (c *cascadeClassifier) Terminate(ctx *core.Context) error {
@@ -63,39 +256,136 @@ func lookupCascadeClassifier(ctx *core.Context, name string) (*cascadeClassifier
 //
 // classifierName: cascadeClassifier state name.
 //
-// img: target image as RawData map structure.
-func DetectMultiScale(ctx *core.Context, classifierName string, img data.Map) (
-	data.Array, error) {
-	raw, err := ConvertMapToRawData(img)
+// img: target image as RawData map structure. Any supported format (e.g.
+// "cvmat", "jpeg") can be used.
+//
+// options: [optional] detection parameters which overwrite the default values
+// of the state, e.g. {"scale_factor":1.2, "min_size":{"width":50,
+// "height":50}}. See NewCascadeClassifier for available parameters. In
+// addition, "polygons" can be set as an array of polygons, each polygon is an
+// array of points which have "x" and "y". When "polygons" is set, the
+// detection runs on the bounding rectangle of the polygons instead of "roi",
+// and only rectangles whose center is inside of any polygons are returned.
+//
+// An error is returned when OpenCV fails to detect, e.g. the parameters are
+// not supported by the classifier.
+func DetectMultiScale(ctx *core.Context, classifierName string, img data.Map,
+	options ...data.Map) (data.Array, error) {
+	d, err := prepareDetection(ctx, classifierName, img, options)
 	if err != nil {
 		return nil, err
 	}
-	mat, err := raw.ToMatVec3b()
-	if err != nil {
-		return nil, err
+	defer d.mat.Delete()
+
+	rects, ok := d.classifier.classifier.DetectMultiScaleWithParams(d.mat,
+		d.params)
+	if !ok {
+		return nil, fmt.Errorf("cannot detect with the state '%v'",
+			classifierName)
 	}
-	defer mat.Delete()
+	ret := make(data.Array, 0, len(rects))
+	for _, r := range rects {
+		if !rectInPolygons(d.polygons, r) {
+			continue
+		}
+		rect := data.Map{
+			"x":      data.Int(r.X),
+			"y":      data.Int(r.Y),
+			"width":  data.Int(r.Width),
+			"height": data.Int(r.Height),
+		}
+		ret = append(ret, rect)
+	}
+	return ret, nil
+}
 
-	classifier, err := lookupCascadeClassifier(ctx, classifierName)
+// DetectMultiScaleWithWeights classifies and detect image like
+// DetectMultiScale, and each detected rectangle has "level" and "weight" in
+// addition to "x", "y", "width" and "height". "level" is the reject level
+// and "weight" is the level weight of the rectangle, the weight can be used
+// as a confidence of the detection.
+func DetectMultiScaleWithWeights(ctx *core.Context, classifierName string,
+	img data.Map, options ...data.Map) (data.Array, error) {
+	d, err := prepareDetection(ctx, classifierName, img, options)
 	if err != nil {
 		return nil, err
 	}
-	rects := classifier.classifier.DetectMultiScale(mat)
-	ret := make(data.Array, len(rects))
-	for i, r := range rects {
+	defer d.mat.Delete()
+
+	rects, ok := d.classifier.classifier.DetectMultiScaleWithWeights(d.mat,
+		d.params)
+	if !ok {
+		return nil, fmt.Errorf("cannot detect with the state '%v'",
+			classifierName)
+	}
+	ret := make(data.Array, 0, len(rects))
+	for _, r := range rects {
+		if !rectInPolygons(d.polygons, r.Rect) {
+			continue
+		}
 		rect := data.Map{
 			"x":      data.Int(r.X),
 			"y":      data.Int(r.Y),
 			"width":  data.Int(r.Width),
 			"height": data.Int(r.Height),
+			"level":  data.Int(r.Level),
+			"weight": data.Float(r.Weight),
 		}
-		ret[i] = rect
+		ret = append(ret, rect)
 	}
 	return ret, nil
 }
 
+type detection struct {
+	classifier *cascadeClassifier
+	mat        bridge.MatVec3b
+	params     bridge.DetectMultiScaleParams
+	polygons   []polygon
+}
+
+// prepareDetection returns the classifier state, the target image and
+// detection parameters. Returned MatVec3b is required to delete after using.
+func prepareDetection(ctx *core.Context, classifierName string, img data.Map,
+	options []data.Map) (*detection, error) {
+	if len(options) > 1 {
+		return nil, fmt.Errorf("detection options must be only one map")
+	}
+
+	classifier, err := lookupCascadeClassifier(ctx, classifierName)
+	if err != nil {
+		return nil, err
+	}
+	d := &detection{
+		classifier: classifier,
+		params:     classifier.params,
+	}
+	if len(options) > 0 {
+		if d.params, err = parseDetectMultiScaleParams(d.params, options[0]); err != nil {
+			return nil, err
+		}
+		if p, err := options[0].Get(polygonsPath); err == nil {
+			if d.polygons, err = convertToPolygons(p); err != nil {
+				return nil, err
+			}
+			if len(d.polygons) > 0 {
+				d.params.ROI = boundingRect(d.polygons)
+			}
+		}
+	}
+
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	if d.mat, err = raw.ToMatVec3b(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
 // DrawRectsToImage draws rectangle information on target image. The image is
-// required to structured as RawData.
+// required to structured as RawData, and returned image has the same format
+// as the target image. Alpha values of "cvmat4b" image are kept.
 func DrawRectsToImage(img data.Map, rects data.Array) (data.Map, error) {
 	if len(rects) == 0 {
 		return img, nil
@@ -104,10 +394,6 @@ func DrawRectsToImage(img data.Map, rects data.Array) (data.Map, error) {
 	if err != nil {
 		return nil, err
 	}
-	// copy image binary
-	temp := make([]byte, len(raw.Data))
-	copy(temp, raw.Data)
-	raw.Data = temp
 	mat, err := raw.ToMatVec3b()
 	if err != nil {
 		return nil, err
@@ -120,52 +406,63 @@ func DrawRectsToImage(img data.Map, rects data.Array) (data.Map, error) {
 	}
 
 	bridge.DrawRectsToImage(mat, brRects)
-	retRaw := ToRawData(mat)
+	retRaw, err := toRawDataWithFormat(mat, raw.Format)
+	if err != nil {
+		return nil, err
+	}
+	retRaw.copyAlpha(&raw)
 	return retRaw.ConvertToDataMap(), nil
 }
 
 func convertToBridgeRects(rects data.Array) ([]bridge.Rect, error) {
 	brRects := make([]bridge.Rect, len(rects))
 	for i, r := range rects {
-		rmap, err := data.AsMap(r)
+		rect, err := convertToBridgeRect(r)
 		if err != nil {
 			return nil, err
 		}
-		var x int64
-		if xv, err := rmap.Get(xPath); err != nil {
-			return nil, err
-		} else if x, err = data.ToInt(xv); err != nil {
-			return nil, err
-		}
-		var y int64
-		if yv, err := rmap.Get(yPath); err != nil {
-			return nil, err
-		} else if y, err = data.ToInt(yv); err != nil {
-			return nil, err
-		}
-		var width int64
-		if wv, err := rmap.Get(widthPath); err != nil {
-			return nil, err
-		} else if width, err = data.ToInt(wv); err != nil {
-			return nil, err
-		}
-		var height int64
-		if hv, err := rmap.Get(heightPath); err != nil {
-			return nil, err
-		} else if height, err = data.ToInt(hv); err != nil {
-			return nil, err
-		}
-		rect := bridge.Rect{
-			X:      int(x),
-			Y:      int(y),
-			Width:  int(width),
-			Height: int(height),
-		}
 		brRects[i] = rect
 	}
 	return brRects, nil
 }
 
+func convertToBridgeRect(r data.Value) (bridge.Rect, error) {
+	rmap, err := data.AsMap(r)
+	if err != nil {
+		return bridge.Rect{}, err
+	}
+	var x int64
+	if xv, err := rmap.Get(xPath); err != nil {
+		return bridge.Rect{}, err
+	} else if x, err = data.ToInt(xv); err != nil {
+		return bridge.Rect{}, err
+	}
+	var y int64
+	if yv, err := rmap.Get(yPath); err != nil {
+		return bridge.Rect{}, err
+	} else if y, err = data.ToInt(yv); err != nil {
+		return bridge.Rect{}, err
+	}
+	var width int64
+	if wv, err := rmap.Get(widthPath); err != nil {
+		return bridge.Rect{}, err
+	} else if width, err = data.ToInt(wv); err != nil {
+		return bridge.Rect{}, err
+	}
+	var height int64
+	if hv, err := rmap.Get(heightPath); err != nil {
+		return bridge.Rect{}, err
+	} else if height, err = data.ToInt(hv); err != nil {
+		return bridge.Rect{}, err
+	}
+	return bridge.Rect{
+		X:      int(x),
+		Y:      int(y),
+		Width:  int(width),
+		Height: int(height),
+	}, nil
+}
+
 // NewSharedImage returns shared image file to reduce I/O cost.
 func NewSharedImage(ctx *core.Context, params data.Map) (core.SharedState, error) {
 	var filePath string
@@ -202,7 +499,8 @@ func lookupSharedImage(ctx *core.Context, name string) (*sharedImage, error) {
 		name)
 }
 
-// MountAlphaImage draw target image on back image.
+// MountAlphaImage draw target image on back image. Returned image has the same
+// format as the back image, and alpha values of "cvmat4b" back image are kept.
 func MountAlphaImage(ctx *core.Context, imgName string, back data.Map,
 	rects data.Array) (data.Map, error) {
 	if len(rects) == 0 {
@@ -217,10 +515,6 @@ func MountAlphaImage(ctx *core.Context, imgName string, back data.Map,
 	if err != nil {
 		return nil, err
 	}
-	// copy image binary
-	temp := make([]byte, len(raw.Data))
-	copy(temp, raw.Data)
-	raw.Data = temp
 	mat, err := raw.ToMatVec3b()
 	if err != nil {
 		return nil, err
@@ -233,6 +527,10 @@ func MountAlphaImage(ctx *core.Context, imgName string, back data.Map,
 	}
 
 	bridge.MountAlphaImage(img.img, mat, brRects)
-	retRaw := ToRawData(mat)
+	retRaw, err := toRawDataWithFormat(mat, raw.Format)
+	if err != nil {
+		return nil, err
+	}
+	retRaw.copyAlpha(&raw)
 	return retRaw.ConvertToDataMap(), nil
 }
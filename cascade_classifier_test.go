@@ -2,6 +2,7 @@ package opencv
 
 import (
 	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
 	"gopkg.in/sensorbee/sensorbee.v0/core"
 	"gopkg.in/sensorbee/sensorbee.v0/data"
 	"io/ioutil"
@@ -73,6 +74,115 @@ func TestNewCascadeClassifier(t *testing.T) {
 				So(ok, ShouldBeTrue)
 				So(cc.classifier, ShouldNotBeNil)
 			})
+			Convey("And detect with downscale which makes the image empty", func() {
+				cc := st.(*cascadeClassifier)
+				img := bridge.ToMatVec3b(4, 4, make([]byte, 4*4*3))
+				defer img.Delete()
+				p := bridge.DefaultDetectMultiScaleParams()
+				p.Downscale = 1000
+				rects, ok := cc.classifier.DetectMultiScaleWithParams(img, p)
+				So(ok, ShouldBeTrue)
+				weighted, ok := cc.classifier.DetectMultiScaleWithWeights(img, p)
+				So(ok, ShouldBeTrue)
+				Convey("Then the detection should not fail", func() {
+					So(rects, ShouldBeEmpty)
+					So(weighted, ShouldBeEmpty)
+				})
+			})
+			Convey("And detect with a parameter which OpenCV rejects", func() {
+				cc := st.(*cascadeClassifier)
+				img := bridge.ToMatVec3b(4, 4, make([]byte, 4*4*3))
+				defer img.Delete()
+				p := bridge.DefaultDetectMultiScaleParams()
+				p.ScaleFactor = 1
+				_, ok := cc.classifier.DetectMultiScaleWithParams(img, p)
+				_, wok := cc.classifier.DetectMultiScaleWithWeights(img, p)
+				Convey("Then the detection should fail", func() {
+					So(ok, ShouldBeFalse)
+					So(wok, ShouldBeFalse)
+				})
+			})
+		})
+	})
+}
+
+func TestParseDetectMultiScaleParams(t *testing.T) {
+	Convey("Given default detection parameters", t, func() {
+		params := bridge.DefaultDetectMultiScaleParams()
+		Convey("When parse a map with full parameters", func() {
+			m := data.Map{
+				"scale_factor":  data.Float(1.2),
+				"min_neighbors": data.Int(5),
+				"flags":         data.Int(2),
+				"min_size": data.Map{
+					"width":  data.Int(30),
+					"height": data.Int(40),
+				},
+				"max_size": data.Map{
+					"width":  data.Int(300),
+					"height": data.Int(400),
+				},
+				"grayscale":        data.True,
+				"equalize":         data.String("clahe"),
+				"clahe_clip_limit": data.Float(4),
+				"clahe_tile_size":  data.Int(16),
+				"downscale":        data.Float(2),
+				"roi": data.Map{
+					"x":      data.Int(10),
+					"y":      data.Int(20),
+					"width":  data.Int(100),
+					"height": data.Int(200),
+				},
+			}
+			p, err := parseDetectMultiScaleParams(params, m)
+			Convey("Then all parameters should be overwritten", func() {
+				So(err, ShouldBeNil)
+				So(p.ScaleFactor, ShouldEqual, 1.2)
+				So(p.MinNeighbors, ShouldEqual, 5)
+				So(p.Flags, ShouldEqual, 2)
+				So(p.MinSize, ShouldResemble, bridge.Size{Width: 30, Height: 40})
+				So(p.MaxSize, ShouldResemble, bridge.Size{Width: 300, Height: 400})
+				So(p.Grayscale, ShouldBeTrue)
+				So(p.Equalization, ShouldEqual, bridge.EqualizationCLAHE)
+				So(p.CLAHEClipLimit, ShouldEqual, 4)
+				So(p.CLAHETileSize, ShouldEqual, 16)
+				So(p.Downscale, ShouldEqual, 2)
+				So(p.ROI, ShouldResemble, bridge.Rect{X: 10, Y: 20, Width: 100,
+					Height: 200})
+			})
+		})
+
+		Convey("When parse an empty map", func() {
+			p, err := parseDetectMultiScaleParams(params, data.Map{})
+			Convey("Then parameters should not be changed", func() {
+				So(err, ShouldBeNil)
+				So(p, ShouldResemble, params)
+			})
+		})
+
+		Convey("When parse a map with invalid parameters", func() {
+			testMap := data.Map{
+				"scale_factor":  data.Float(1.0),
+				"min_neighbors": data.Int(-1),
+				"flags":         data.String("a"),
+				"min_size":      data.Int(30),
+				"max_size": data.Map{
+					"width": data.Int(300),
+				},
+				"grayscale":        data.String("yes"),
+				"equalize":         data.String("gamma"),
+				"clahe_clip_limit": data.Float(0),
+				"clahe_tile_size":  data.Int(0),
+				"downscale":        data.Float(0.5),
+				"roi":              data.String("door"),
+			}
+			for k, v := range testMap {
+				v := v
+				Convey("Then an error should be occurred with "+k, func() {
+					_, err := parseDetectMultiScaleParams(params, data.Map{k: v})
+					So(err, ShouldNotBeNil)
+				})
+			}
 		})
 	})
 }
@@ -104,3 +214,88 @@ func TestNewSharedImage(t *testing.T) {
 		})
 	})
 }
+
+func TestDetectMultiScaleWithWeightsError(t *testing.T) {
+	Convey("Given a SensorBee's core.Context without classifier state", t, func() {
+		ctx := core.NewContext(&core.ContextConfig{})
+		raw := RawData{
+			Format: TypeCVMAT,
+			Width:  8,
+			Height: 8,
+			Data:   make([]byte, 8*8*3),
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When detect with not exist state name", func() {
+			_, err := DetectMultiScaleWithWeights(ctx, "not_exist", img)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When detect with too many options", func() {
+			_, err := DetectMultiScaleWithWeights(ctx, "not_exist", img,
+				data.Map{}, data.Map{})
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestDrawRectsToImage(t *testing.T) {
+	Convey("Given a cvmat image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Width:  8,
+			Height: 8,
+			Data:   make([]byte, 8*8*3),
+		}
+		rects := data.Array{
+			data.Map{
+				"x":      data.Int(1),
+				"y":      data.Int(1),
+				"width":  data.Int(4),
+				"height": data.Int(4),
+			},
+		}
+		for _, f := range []TypeImageFormat{TypeCVMAT, TypeCVMAT4b, TypeJPEG,
+			TypePNG} {
+			f := f
+			Convey("When draw rects on the image as "+f.String(), func() {
+				var img data.Map
+				switch f {
+				case TypeCVMAT:
+					img = raw.ConvertToDataMap()
+				case TypeCVMAT4b:
+					raw4b := RawData{
+						Format: TypeCVMAT4b,
+						Width:  8,
+						Height: 8,
+						Data:   make([]byte, 8*8*4),
+					}
+					for i := 3; i < len(raw4b.Data); i += 4 {
+						raw4b.Data[i] = byte(i)
+					}
+					img = raw4b.ConvertToDataMap()
+				default:
+					encoded, err := EncodeImage(raw.ConvertToDataMap(), f.String())
+					So(err, ShouldBeNil)
+					img = encoded
+				}
+				ret, err := DrawRectsToImage(img, rects)
+				So(err, ShouldBeNil)
+				Convey("Then the returned image should be the same format", func() {
+					retRaw, err := ConvertMapToRawData(ret)
+					So(err, ShouldBeNil)
+					So(retRaw.Format, ShouldEqual, f)
+					So(retRaw.Width, ShouldEqual, 8)
+					So(retRaw.Height, ShouldEqual, 8)
+					if f == TypeCVMAT4b {
+						for i := 3; i < len(retRaw.Data); i += 4 {
+							So(retRaw.Data[i], ShouldEqual, byte(i))
+						}
+					}
+				})
+			})
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package opencv
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"image"
+	_ "image/gif" // for FromImageBlob
+)
+
+var (
+	qualityPath     = data.MustCompilePath("quality")
+	compressionPath = data.MustCompilePath("compression")
+)
+
+// EncodeImage encodes the image to the compressed format.
+//
+// img: target image as RawData map structure.
+//
+// format: "jpeg", "png", "webp" or "bmp". "webp" is available when the linked
+// OpenCV supports it.
+//
+// params: [optional] encoding parameters. "quality" is JPEG quality (0-100)
+// or WebP quality (1-100), "compression" is PNG compression level (0-9).
+func EncodeImage(img data.Map, format string, params ...data.Map) (data.Map,
+	error) {
+	if len(params) > 1 {
+		return nil, fmt.Errorf("encoding parameters must be only one map")
+	}
+
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	f := GetTypeImageFormat(format)
+	if !f.IsCompressed() {
+		return nil, fmt.Errorf("'%v' is not an encoding format", format)
+	}
+
+	var cvParams []int
+	if len(params) > 0 {
+		if cvParams, err = toEncodeParams(f, params[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := raw.Encode(f, cvParams...)
+	if err != nil {
+		return nil, err
+	}
+	return encoded.ConvertToDataMap(), nil
+}
+
+func toEncodeParams(format TypeImageFormat, params data.Map) ([]int, error) {
+	cvParams := []int{}
+	for k := range params {
+		switch k {
+		case "quality", "compression":
+		default:
+			return nil, fmt.Errorf("'%v' is not an encoding parameter", k)
+		}
+	}
+
+	if q, err := params.Get(qualityPath); err == nil {
+		quality, err := data.ToInt(q)
+		if err != nil {
+			return nil, err
+		}
+		switch format {
+		case TypeJPEG:
+			cvParams = append(cvParams, bridge.CvImwriteJpegQuality, int(quality))
+		case TypeWEBP:
+			cvParams = append(cvParams, bridge.CvImwriteWebpQuality, int(quality))
+		default:
+			return nil, fmt.Errorf("'%v' does not support quality", format)
+		}
+	}
+
+	if c, err := params.Get(compressionPath); err == nil {
+		compression, err := data.ToInt(c)
+		if err != nil {
+			return nil, err
+		}
+		if format != TypePNG {
+			return nil, fmt.Errorf("'%v' does not support compression", format)
+		}
+		cvParams = append(cvParams, bridge.CvImwritePngCompression,
+			int(compression))
+	}
+	return cvParams, nil
+}
+
+// DecodeImage decodes the compressed image to RawData map structure.
+//
+// img: target image as blob or RawData map structure.
+//
+// format: [optional] "cvmat", "cvmat4b" or "cvmat1b" (grayscale), default is
+// "cvmat".
+func DecodeImage(img data.Value, format ...string) (data.Map, error) {
+	if len(format) > 1 {
+		return nil, fmt.Errorf("decoding format must be only one")
+	}
+	f := TypeCVMAT
+	if len(format) > 0 {
+		f = GetTypeImageFormat(format[0])
+		if f.channels() == 0 {
+			return nil, fmt.Errorf("cannot decode to '%v'", format[0])
+		}
+	}
+
+	var decoded RawData
+	switch img.Type() {
+	case data.TypeBlob:
+		b, err := data.AsBlob(img)
+		if err != nil {
+			return nil, err
+		}
+		if decoded, err = decodeImageData(b, f); err != nil {
+			return nil, err
+		}
+	case data.TypeMap:
+		m, err := data.AsMap(img)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := ConvertMapToRawData(m)
+		if err != nil {
+			return nil, err
+		}
+		if decoded, err = raw.Decode(f); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("'%v' cannot be decoded", img.Type())
+	}
+	return decoded.ConvertToDataMap(), nil
+}
+
+// FromImageBlob decodes the image data with the standard image package, and
+// returns it as "cvmat" RawData map structure. Any format registered to the
+// image package is supported, e.g. JPEG, PNG and GIF, and other formats are
+// available when their packages are imported to the binary (e.g.
+// golang.org/x/image/webp).
+//
+// b: image data as blob.
+func FromImageBlob(b []byte) (data.Map, error) {
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	raw := FromImage(img)
+	if raw.Format != TypeCVMAT {
+		if raw, err = raw.ConvertColor(ModeBGR); err != nil {
+			return nil, err
+		}
+	}
+	return raw.ConvertToDataMap(), nil
+}
@@ -0,0 +1,173 @@
+package opencv
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestEncodeAndDecodeImage(t *testing.T) {
+	Convey("Given a cvmat image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Width:  2,
+			Height: 2,
+			Data: []byte{
+				0, 0, 255, 0, 255, 0,
+				255, 0, 0, 255, 255, 255,
+			},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When encode it to png", func() {
+			encoded, err := EncodeImage(img, "png", data.Map{
+				"compression": data.Int(9),
+			})
+			So(err, ShouldBeNil)
+			Convey("Then the image should be png format", func() {
+				f, err := encoded.Get(formatPath)
+				So(err, ShouldBeNil)
+				So(f, ShouldResemble, data.String("png"))
+			})
+			Convey("And decode it", func() {
+				decoded, err := DecodeImage(encoded)
+				So(err, ShouldBeNil)
+				Convey("Then the image should be same as the original", func() {
+					So(decoded, ShouldResemble, img)
+				})
+			})
+			Convey("And decode it from blob", func() {
+				b, err := encoded.Get(imagePath)
+				So(err, ShouldBeNil)
+				decoded, err := DecodeImage(b, "cvmat4b")
+				So(err, ShouldBeNil)
+				Convey("Then the image should have alpha channel", func() {
+					decRaw, err := ConvertMapToRawData(decoded)
+					So(err, ShouldBeNil)
+					So(decRaw.Format, ShouldEqual, TypeCVMAT4b)
+					So(decRaw.Data, ShouldResemble, []byte{
+						0, 0, 255, 255, 0, 255, 0, 255,
+						255, 0, 0, 255, 255, 255, 255, 255,
+					})
+				})
+			})
+		})
+
+		Convey("When encode it to bmp", func() {
+			encoded, err := EncodeImage(img, "bmp")
+			So(err, ShouldBeNil)
+			Convey("Then the image should be decoded to the original", func() {
+				decoded, err := DecodeImage(encoded)
+				So(err, ShouldBeNil)
+				So(decoded, ShouldResemble, img)
+			})
+		})
+
+		Convey("When encode it to not compressed format", func() {
+			_, err := EncodeImage(img, "cvmat4b")
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When encode it with invalid parameters", func() {
+			testParams := []data.Map{
+				{"quality": data.Int(90)},
+				{"compression": data.String("a")},
+				{"level": data.Int(1)},
+			}
+			for _, p := range testParams {
+				_, err := EncodeImage(img, "png", p)
+				Convey("Then an error should be occurred with "+p.String(), func() {
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+	})
+
+	Convey("Given an invalid image blob", t, func() {
+		b := data.Blob([]byte("not an image"))
+		Convey("When decode it", func() {
+			_, err := DecodeImage(b)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestEncodeAndDecodeGrayImage(t *testing.T) {
+	Convey("Given a cvmat1b image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT1b,
+			Width:  2,
+			Height: 2,
+			Data:   []byte{0, 64, 128, 255},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When encode it to png", func() {
+			encoded, err := EncodeImage(img, "png")
+			So(err, ShouldBeNil)
+			Convey("And decode it as cvmat1b", func() {
+				decoded, err := DecodeImage(encoded, "cvmat1b")
+				So(err, ShouldBeNil)
+				Convey("Then the image should be same as the original", func() {
+					So(decoded, ShouldResemble, img)
+					So(decoded["mode"], ShouldEqual, data.String("GRAY"))
+				})
+			})
+		})
+		Convey("When convert it to MatVec3b", func() {
+			mat, err := raw.ToMatVec3b()
+			So(err, ShouldBeNil)
+			defer mat.Delete()
+			Convey("Then each channel should have the gray value", func() {
+				bgr := ToRawData(mat)
+				So(bgr.Data, ShouldResemble, []byte{
+					0, 0, 0, 64, 64, 64, 128, 128, 128, 255, 255, 255,
+				})
+			})
+		})
+		Convey("When convert it with short data to MatGray", func() {
+			raw.Data = raw.Data[:3]
+			_, err := raw.ToMatGray()
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestFromImageBlob(t *testing.T) {
+	Convey("Given PNG data of a grayscale image.Image", t, func() {
+		src := image.NewGray(image.Rect(0, 0, 2, 1))
+		copy(src.Pix, []byte{0, 255})
+		w := bytes.NewBuffer([]byte{})
+		So(png.Encode(w, src), ShouldBeNil)
+		Convey("When decode it", func() {
+			img, err := FromImageBlob(w.Bytes())
+			So(err, ShouldBeNil)
+			Convey("Then the image should be cvmat in BGR", func() {
+				So(img, ShouldResemble, data.Map{
+					"format": data.String("cvmat"),
+					"mode":   data.String("BGR"),
+					"width":  data.Int(2),
+					"height": data.Int(1),
+					"image":  data.Blob([]byte{0, 0, 0, 255, 255, 255}),
+				})
+			})
+		})
+	})
+
+	Convey("Given data which is not an image", t, func() {
+		b := []byte("not an image")
+		Convey("When decode it", func() {
+			_, err := FromImageBlob(b)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
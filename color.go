@@ -0,0 +1,121 @@
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"strings"
+)
+
+// toBGRCodes are color conversion codes from each mode to BGR.
+var toBGRCodes = map[TypeColorMode]int{
+	ModeRGB:   bridge.ColorRGB2BGR,
+	ModeGRAY:  bridge.ColorGRAY2BGR,
+	ModeHSV:   bridge.ColorHSV2BGR,
+	ModeYCrCb: bridge.ColorYCrCb2BGR,
+	ModeBGRA:  bridge.ColorBGRA2BGR,
+}
+
+// fromBGRCodes are color conversion codes from BGR to each mode.
+var fromBGRCodes = map[TypeColorMode]int{
+	ModeRGB:   bridge.ColorBGR2RGB,
+	ModeGRAY:  bridge.ColorBGR2GRAY,
+	ModeHSV:   bridge.ColorBGR2HSV,
+	ModeYCrCb: bridge.ColorBGR2YCrCb,
+	ModeBGRA:  bridge.ColorBGR2BGRA,
+}
+
+// ConvertColor converts RawData to the color mode with `cv::cvtColor`. The
+// format of the result is decided by the number of channels of the mode,
+// i.e. cvmat, cvmat4b or cvmat1b. Compressed image data is decoded as BGR.
+// Modes other than BGR are converted via BGR.
+func (r *RawData) ConvertColor(mode TypeColorMode) (RawData, error) {
+	if mode.channels() == 0 {
+		return RawData{}, fmt.Errorf("'%v' mode is not supported", mode)
+	}
+	src := *r
+	if src.Format.IsCompressed() {
+		decoded, err := src.Decode(TypeCVMAT)
+		if err != nil {
+			return RawData{}, err
+		}
+		src = decoded
+	}
+	if src.Format.channels() == 0 {
+		return RawData{}, fmt.Errorf("'%v' cannot convert color", src.Format)
+	}
+	srcMode := src.ColorMode()
+	if srcMode == mode {
+		src.Mode = mode
+		return src, nil
+	}
+
+	b := src.Data
+	channels := src.Format.channels()
+	if srcMode != ModeBGR {
+		var ok bool
+		b, channels, ok = bridge.CvtColor(b, src.Width, src.Height, channels,
+			toBGRCodes[srcMode])
+		if !ok {
+			return RawData{}, fmt.Errorf("cannot convert '%v' to 'BGR'", srcMode)
+		}
+	}
+	if mode != ModeBGR {
+		var ok bool
+		b, channels, ok = bridge.CvtColor(b, src.Width, src.Height, channels,
+			fromBGRCodes[mode])
+		if !ok {
+			return RawData{}, fmt.Errorf("cannot convert 'BGR' to '%v'", mode)
+		}
+	}
+	return RawData{
+		Format: rawFormatOfChannels(channels),
+		Mode:   mode,
+		Width:  src.Width,
+		Height: src.Height,
+		Data:   b,
+	}, nil
+}
+
+// CvtColor converts the color mode of the image, and returns RawData map
+// structure of which format has the number of channels of the mode (e.g.
+// "cvmat1b" for "GRAY"). Returned map has "mode" field.
+//
+// img: target image as RawData map structure.
+//
+// code: the destination mode, "BGR", "RGB", "GRAY", "HSV", "YCrCb" or
+// "BGRA". OpenCV style code such as "BGR2GRAY" is also accepted, and then the
+// source mode is required to be the same as the mode of the image.
+func CvtColor(img data.Map, code string) (data.Map, error) {
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := code
+	if i := strings.Index(code, "2"); i >= 0 {
+		src, err := GetTypeColorMode(code[:i])
+		if err != nil {
+			return nil, err
+		}
+		imgMode := raw.ColorMode()
+		if raw.Format.IsCompressed() {
+			imgMode = ModeBGR
+		}
+		if src != imgMode {
+			return nil, fmt.Errorf("'%v' does not match the mode of the image: %v",
+				code, imgMode)
+		}
+		dst = code[i+1:]
+	}
+	mode, err := GetTypeColorMode(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	converted, err := raw.ConvertColor(mode)
+	if err != nil {
+		return nil, err
+	}
+	return converted.ConvertToDataMap(), nil
+}
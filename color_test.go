@@ -0,0 +1,97 @@
+package opencv
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"testing"
+)
+
+func TestColorModeOfRawData(t *testing.T) {
+	Convey("Given a cvmat image map without mode", t, func() {
+		img := data.Map{
+			"format": data.String("cvmat"),
+			"width":  data.Int(1),
+			"height": data.Int(1),
+			"image":  data.Blob([]byte{1, 2, 3}),
+		}
+		Convey("When convert it to RawData", func() {
+			raw, err := ConvertMapToRawData(img)
+			So(err, ShouldBeNil)
+			Convey("Then the mode should be BGR", func() {
+				So(raw.ColorMode(), ShouldEqual, ModeBGR)
+				m := raw.ConvertToDataMap()
+				So(m["mode"], ShouldEqual, data.String("BGR"))
+			})
+		})
+		Convey("When the map has a mode which does not match the format", func() {
+			img["mode"] = data.String("GRAY")
+			_, err := ConvertMapToRawData(img)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When the map has not supported mode", func() {
+			img["mode"] = data.String("CMYK")
+			_, err := ConvertMapToRawData(img)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestCvtColor(t *testing.T) {
+	Convey("Given a 2x1 BGR cvmat image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Width:  2,
+			Height: 1,
+			Data:   []byte{1, 2, 3, 255, 255, 255},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When convert it to RGB", func() {
+			converted, err := CvtColor(img, "RGB")
+			So(err, ShouldBeNil)
+			Convey("Then channels should be swapped", func() {
+				So(converted, ShouldResemble, data.Map{
+					"format": data.String("cvmat"),
+					"mode":   data.String("RGB"),
+					"width":  data.Int(2),
+					"height": data.Int(1),
+					"image":  data.Blob([]byte{3, 2, 1, 255, 255, 255}),
+				})
+			})
+			Convey("And convert it back to BGR", func() {
+				back, err := CvtColor(converted, "RGB2BGR")
+				So(err, ShouldBeNil)
+				Convey("Then the image should be the same as the original", func() {
+					So(back, ShouldResemble, img)
+				})
+			})
+		})
+		Convey("When convert it to GRAY", func() {
+			converted, err := CvtColor(img, "BGR2GRAY")
+			So(err, ShouldBeNil)
+			Convey("Then the image should have single channel", func() {
+				r, err := ConvertMapToRawData(converted)
+				So(err, ShouldBeNil)
+				So(r.Format, ShouldEqual, TypeCVMAT1b)
+				So(r.Mode, ShouldEqual, ModeGRAY)
+				So(len(r.Data), ShouldEqual, 2)
+				So(r.Data[1], ShouldEqual, 255)
+			})
+		})
+		Convey("When convert it with the code of which source does not match", func() {
+			_, err := CvtColor(img, "HSV2BGR")
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When convert it to not supported mode", func() {
+			_, err := CvtColor(img, "XYZ")
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
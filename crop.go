@@ -0,0 +1,142 @@
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+)
+
+// clampRect returns the intersection of the rectangle and the image. Returns
+// false when they do not intersect.
+func clampRect(r bridge.Rect, width, height int) (bridge.Rect, bool) {
+	left, top := r.X, r.Y
+	right, bottom := r.X+r.Width, r.Y+r.Height
+	if left < 0 {
+		left = 0
+	}
+	if top < 0 {
+		top = 0
+	}
+	if right > width {
+		right = width
+	}
+	if bottom > height {
+		bottom = height
+	}
+	if right <= left || bottom <= top {
+		return bridge.Rect{}, false
+	}
+	return bridge.Rect{
+		X:      left,
+		Y:      top,
+		Width:  right - left,
+		Height: bottom - top,
+	}, true
+}
+
+// Crop returns the region of the rectangle as RawData of the same format. The
+// rectangle is clamped to the image. Raw image formats (e.g. cvmat) are
+// cropped without OpenCV, and compressed image data is decoded and encoded
+// again.
+func (r *RawData) Crop(rect bridge.Rect) (RawData, error) {
+	roi, ok := clampRect(rect, r.Width, r.Height)
+	if !ok {
+		return RawData{}, fmt.Errorf("rectangle is out of the image: %v", rect)
+	}
+
+	if r.Format.IsCompressed() {
+		mat, err := r.ToMatVec3b()
+		if err != nil {
+			return RawData{}, err
+		}
+		defer mat.Delete()
+		region := mat.Region(roi)
+		defer region.Delete()
+		return toRawDataWithFormat(region, r.Format)
+	}
+	channels := r.Format.channels()
+	if channels == 0 {
+		return RawData{}, fmt.Errorf("'%v' cannot be cropped", r.Format)
+	}
+	if err := r.checkSize(); err != nil {
+		return RawData{}, err
+	}
+
+	stride := r.Width * channels
+	rowSize := roi.Width * channels
+	b := make([]byte, rowSize*roi.Height)
+	for y := 0; y < roi.Height; y++ {
+		start := (roi.Y+y)*stride + roi.X*channels
+		copy(b[y*rowSize:(y+1)*rowSize], r.Data[start:start+rowSize])
+	}
+	return RawData{
+		Format: r.Format,
+		Mode:   r.Mode,
+		Width:  roi.Width,
+		Height: roi.Height,
+		Data:   b,
+	}, nil
+}
+
+// Crop returns the region of the rectangle as RawData map structure of the
+// same format as the image. The rectangle is clamped to the image, and when
+// the rectangle is out of the image, returns an error.
+//
+// img: target image as RawData map structure.
+//
+// rect: a map which has "x", "y", "width" and "height", e.g. an element of the
+// result of opencv_detect_multi_scale.
+func Crop(img data.Map, rect data.Map) (data.Map, error) {
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	r, err := convertToBridgeRect(rect)
+	if err != nil {
+		return nil, err
+	}
+	cropped, err := raw.Crop(r)
+	if err != nil {
+		return nil, err
+	}
+	return cropped.ConvertToDataMap(), nil
+}
+
+// CropAll returns regions of rectangles as an array of RawData map structure
+// in the same order as rectangles. See Crop for details.
+//
+// img: target image as RawData map structure.
+//
+// rects: an array of rectangles, e.g. the result of opencv_detect_multi_scale.
+func CropAll(img data.Map, rects data.Array) (data.Array, error) {
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := convertToBridgeRects(rects)
+	if err != nil {
+		return nil, err
+	}
+
+	format := raw.Format
+	if format.IsCompressed() {
+		// decode only once for all rectangles
+		if raw, err = raw.Decode(TypeCVMAT); err != nil {
+			return nil, err
+		}
+	}
+	ret := make(data.Array, len(rs))
+	for i, r := range rs {
+		cropped, err := raw.Crop(r)
+		if err != nil {
+			return nil, err
+		}
+		if format.IsCompressed() {
+			if cropped, err = cropped.Encode(format); err != nil {
+				return nil, err
+			}
+		}
+		ret[i] = cropped.ConvertToDataMap()
+	}
+	return ret, nil
+}
@@ -0,0 +1,132 @@
+package opencv
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"testing"
+)
+
+func TestClampRect(t *testing.T) {
+	Convey("Given a 10x10 image size", t, func() {
+		Convey("When clamp a rectangle which sticks out of the image", func() {
+			r, ok := clampRect(bridge.Rect{X: -2, Y: 5, Width: 5, Height: 10},
+				10, 10)
+			Convey("Then the rectangle should be clamped", func() {
+				So(ok, ShouldBeTrue)
+				So(r, ShouldResemble, bridge.Rect{X: 0, Y: 5, Width: 3, Height: 5})
+			})
+		})
+		Convey("When clamp a rectangle which is out of the image", func() {
+			_, ok := clampRect(bridge.Rect{X: 10, Y: 0, Width: 5, Height: 5},
+				10, 10)
+			Convey("Then the rectangle should be invalid", func() {
+				So(ok, ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestCrop(t *testing.T) {
+	Convey("Given a 3x2 cvmat4b image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT4b,
+			Width:  3,
+			Height: 2,
+			Data: []byte{
+				0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2,
+				3, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5,
+			},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When crop the right bottom region", func() {
+			cropped, err := Crop(img, data.Map{
+				"x":      data.Int(1),
+				"y":      data.Int(1),
+				"width":  data.Int(5),
+				"height": data.Int(5),
+			})
+			So(err, ShouldBeNil)
+			Convey("Then the clamped region should be returned", func() {
+				expected := RawData{
+					Format: TypeCVMAT4b,
+					Width:  2,
+					Height: 1,
+					Data:   []byte{4, 4, 4, 4, 5, 5, 5, 5},
+				}
+				So(cropped, ShouldResemble, expected.ConvertToDataMap())
+			})
+		})
+		Convey("When crop a region out of the image", func() {
+			_, err := Crop(img, data.Map{
+				"x":      data.Int(3),
+				"y":      data.Int(0),
+				"width":  data.Int(1),
+				"height": data.Int(1),
+			})
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given a 2x2 cvmat image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Width:  2,
+			Height: 2,
+			Data: []byte{
+				0, 0, 0, 1, 1, 1,
+				2, 2, 2, 3, 3, 3,
+			},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When crop all columns", func() {
+			rects := data.Array{
+				data.Map{
+					"x":      data.Int(0),
+					"y":      data.Int(0),
+					"width":  data.Int(1),
+					"height": data.Int(2),
+				},
+				data.Map{
+					"x":      data.Int(1),
+					"y":      data.Int(0),
+					"width":  data.Int(1),
+					"height": data.Int(2),
+				},
+			}
+			cropped, err := CropAll(img, rects)
+			So(err, ShouldBeNil)
+			Convey("Then each column should be returned in order", func() {
+				So(len(cropped), ShouldEqual, 2)
+				left := RawData{
+					Format: TypeCVMAT,
+					Width:  1,
+					Height: 2,
+					Data:   []byte{0, 0, 0, 2, 2, 2},
+				}
+				right := RawData{
+					Format: TypeCVMAT,
+					Width:  1,
+					Height: 2,
+					Data:   []byte{1, 1, 1, 3, 3, 3},
+				}
+				So(cropped[0], ShouldResemble, left.ConvertToDataMap())
+				So(cropped[1], ShouldResemble, right.ConvertToDataMap())
+			})
+		})
+		Convey("When crop with data shorter than the size", func() {
+			raw.Data = raw.Data[:6]
+			_, err := Crop(raw.ConvertToDataMap(), data.Map{
+				"x":      data.Int(0),
+				"y":      data.Int(0),
+				"width":  data.Int(1),
+				"height": data.Int(1),
+			})
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,185 @@
+//go:build dnn
+// +build dnn
+
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"math"
+	"sort"
+)
+
+var (
+	topKPath    = data.MustCompilePath("top_k")
+	softmaxPath = data.MustCompilePath("softmax")
+)
+
+type dnnClassifyParams struct {
+	topK    int
+	softmax bool
+}
+
+func defaultDnnClassifyParams() dnnClassifyParams {
+	return dnnClassifyParams{
+		topK:    5,
+		softmax: false,
+	}
+}
+
+// parseDnnClassifyParams returns classification parameters which are
+// overwritten by values in the map.
+func parseDnnClassifyParams(params dnnClassifyParams, m data.Map) (
+	dnnClassifyParams, error) {
+	if k, err := m.Get(topKPath); err == nil {
+		topK, err := data.ToInt(k)
+		if err != nil {
+			return params, err
+		}
+		if topK <= 0 {
+			return params, fmt.Errorf("top_k must be greater than 0: %v", topK)
+		}
+		params.topK = int(topK)
+	}
+
+	if s, err := m.Get(softmaxPath); err == nil {
+		if params.softmax, err = data.AsBool(s); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+type dnnClass struct {
+	class int
+	score float32
+}
+
+type byScore []dnnClass
+
+func (s byScore) Len() int           { return len(s) }
+func (s byScore) Less(i, j int) bool { return s[i].score > s[j].score }
+func (s byScore) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// topKClasses returns the k classes of which scores are the highest in
+// descending order. When softmax is true, scores are normalized by softmax
+// before sorting.
+func topKClasses(scores []float32, k int, softmax bool) []dnnClass {
+	if softmax {
+		scores = softmaxScores(scores)
+	}
+	classes := make([]dnnClass, len(scores))
+	for i, s := range scores {
+		classes[i] = dnnClass{class: i, score: s}
+	}
+	sort.Stable(byScore(classes))
+	if len(classes) > k {
+		classes = classes[:k]
+	}
+	return classes
+}
+
+func softmaxScores(scores []float32) []float32 {
+	if len(scores) == 0 {
+		return scores
+	}
+	max := scores[0]
+	for _, s := range scores {
+		if s > max {
+			max = s
+		}
+	}
+	ret := make([]float32, len(scores))
+	sum := 0.0
+	for i, s := range scores {
+		e := math.Exp(float64(s - max))
+		ret[i] = float32(e)
+		sum += e
+	}
+	for i := range ret {
+		ret[i] = float32(float64(ret[i]) / sum)
+	}
+	return ret
+}
+
+// DnnClassify runs forward pass of the network with the image, and returns
+// the top-k classes as an array of maps which have "class", "label" and
+// "score" in descending order of score. "label" is an empty string when
+// labels of the state are not set or the class ID is out of them. All values
+// of the output tensor are regarded as scores of classes, e.g. [1, 1000] for
+// ImageNet models.
+//
+// netName: dnnNet state name.
+//
+// img: target image as RawData map structure.
+//
+// options: [optional] blob and classification parameters which overwrite the
+// default values of the state. See NewDnnNet for available parameters. In
+// addition, "roi" can be set as a map which has "x", "y", "width" and
+// "height" to classify the region of the image, e.g. a rectangle returned by
+// opencv_dnn_detect. The region is clamped to the image.
+func DnnClassify(ctx *core.Context, netName string, img data.Map,
+	options ...data.Map) (data.Array, error) {
+	if len(options) > 1 {
+		return nil, fmt.Errorf("classification options must be only one map")
+	}
+	net, err := lookupDnnNet(ctx, netName)
+	if err != nil {
+		return nil, err
+	}
+	blobParams := net.params
+	classifyParams := net.classifyParams
+	var roi *bridge.Rect
+	if len(options) > 0 {
+		if blobParams, err = parseBlobParams(blobParams, options[0]); err != nil {
+			return nil, err
+		}
+		if classifyParams, err = parseDnnClassifyParams(classifyParams,
+			options[0]); err != nil {
+			return nil, err
+		}
+		if r, err := options[0].Get(roiPath); err == nil {
+			rect, err := convertToBridgeRect(r)
+			if err != nil {
+				return nil, err
+			}
+			roi = &rect
+		}
+	}
+
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	mat, err := raw.ToMatVec3b()
+	if err != nil {
+		return nil, err
+	}
+	defer mat.Delete()
+	target := mat
+	if roi != nil {
+		region := mat.Region(*roi)
+		defer region.Delete()
+		if region.Empty() {
+			return nil, fmt.Errorf("roi is out of the image: %v", *roi)
+		}
+		target = region
+	}
+
+	t, err := net.forward(target, blobParams)
+	if err != nil {
+		return nil, err
+	}
+	classes := topKClasses(t.Data, classifyParams.topK, classifyParams.softmax)
+	ret := make(data.Array, len(classes))
+	for i, c := range classes {
+		ret[i] = data.Map{
+			"class": data.Int(c.class),
+			"label": data.String(net.label(c.class)),
+			"score": data.Float(c.score),
+		}
+	}
+	return ret, nil
+}
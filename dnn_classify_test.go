@@ -0,0 +1,82 @@
+//go:build dnn
+// +build dnn
+
+package opencv
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"testing"
+)
+
+func TestParseDnnClassifyParams(t *testing.T) {
+	Convey("Given default dnn classification parameters", t, func() {
+		params := defaultDnnClassifyParams()
+		Convey("When parse a map with full parameters", func() {
+			m := data.Map{
+				"top_k":   data.Int(3),
+				"softmax": data.True,
+			}
+			p, err := parseDnnClassifyParams(params, m)
+			Convey("Then all parameters should be overwritten", func() {
+				So(err, ShouldBeNil)
+				So(p, ShouldResemble, dnnClassifyParams{
+					topK:    3,
+					softmax: true,
+				})
+			})
+		})
+
+		Convey("When parse a map with invalid parameters", func() {
+			testMap := data.Map{
+				"top_k":   data.Int(0),
+				"softmax": data.String("true"),
+			}
+			for k, v := range testMap {
+				v := v
+				Convey("Then an error should be occurred with "+k, func() {
+					_, err := parseDnnClassifyParams(params, data.Map{k: v})
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestTopKClasses(t *testing.T) {
+	Convey("Given scores of 4 classes", t, func() {
+		scores := []float32{0.1, 0.4, 0.2, 0.3}
+		Convey("When get top 2 classes", func() {
+			classes := topKClasses(scores, 2, false)
+			Convey("Then the 2 highest classes should be returned", func() {
+				So(classes, ShouldResemble, []dnnClass{
+					{class: 1, score: 0.4},
+					{class: 3, score: 0.3},
+				})
+			})
+		})
+		Convey("When get more classes than scores", func() {
+			classes := topKClasses(scores, 10, false)
+			Convey("Then all classes should be returned", func() {
+				So(len(classes), ShouldEqual, 4)
+				So(classes[3].class, ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("Given logits of 3 classes", t, func() {
+		scores := []float32{1, 3, 2}
+		Convey("When get top classes with softmax", func() {
+			classes := topKClasses(scores, 3, true)
+			Convey("Then scores should be normalized", func() {
+				So(classes[0].class, ShouldEqual, 1)
+				So(classes[0].score, ShouldAlmostEqual, 0.6652, 0.0001)
+				sum := float32(0)
+				for _, c := range classes {
+					sum += c.score
+				}
+				So(sum, ShouldAlmostEqual, 1, 0.0001)
+			})
+		})
+	})
+}
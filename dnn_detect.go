@@ -0,0 +1,277 @@
+//go:build dnn
+// +build dnn
+
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"sort"
+)
+
+var (
+	decoderPath             = data.MustCompilePath("decoder")
+	confidenceThresholdPath = data.MustCompilePath("confidence_threshold")
+	nmsThresholdPath        = data.MustCompilePath("nms_threshold")
+)
+
+type dnnDetectParams struct {
+	decoder             string
+	confidenceThreshold float32
+	nmsThreshold        float32
+}
+
+func defaultDnnDetectParams() dnnDetectParams {
+	return dnnDetectParams{
+		decoder:             "ssd",
+		confidenceThreshold: 0.5,
+		nmsThreshold:        0.4,
+	}
+}
+
+// parseDnnDetectParams returns detection parameters which are overwritten by
+// values in the map.
+func parseDnnDetectParams(params dnnDetectParams, m data.Map) (dnnDetectParams,
+	error) {
+	if d, err := m.Get(decoderPath); err == nil {
+		if params.decoder, err = data.AsString(d); err != nil {
+			return params, err
+		}
+		switch params.decoder {
+		case "ssd", "yolo":
+		default:
+			return params, fmt.Errorf("'%v' decoder is not supported",
+				params.decoder)
+		}
+	}
+
+	if ct, err := m.Get(confidenceThresholdPath); err == nil {
+		t, err := data.ToFloat(ct)
+		if err != nil {
+			return params, err
+		}
+		if t < 0 || t > 1 {
+			return params, fmt.Errorf(
+				"confidence_threshold must be in [0, 1]: %v", t)
+		}
+		params.confidenceThreshold = float32(t)
+	}
+
+	if nt, err := m.Get(nmsThresholdPath); err == nil {
+		t, err := data.ToFloat(nt)
+		if err != nil {
+			return params, err
+		}
+		if t < 0 || t > 1 {
+			return params, fmt.Errorf("nms_threshold must be in [0, 1]: %v", t)
+		}
+		params.nmsThreshold = float32(t)
+	}
+	return params, nil
+}
+
+type dnnDetection struct {
+	rect  bridge.Rect
+	class int
+	score float32
+}
+
+// decodeSSD decodes SSD style outputs. Each output has [1, 1, N, 7] shape, and
+// each detection is [image ID, class ID, score, left, top, right, bottom]
+// with coordinates normalized by the image size.
+func decodeSSD(ts []bridge.Tensor, width, height int,
+	threshold float32) ([]dnnDetection, error) {
+	dets := []dnnDetection{}
+	for _, t := range ts {
+		if len(t.Shape) == 0 || t.Shape[len(t.Shape)-1] != 7 {
+			return nil, fmt.Errorf("output of SSD must be [1, 1, N, 7]: %v",
+				t.Shape)
+		}
+		for i := 0; i+7 <= len(t.Data); i += 7 {
+			d := t.Data[i : i+7]
+			score := d[2]
+			if score < threshold {
+				continue
+			}
+			left := int(d[3] * float32(width))
+			top := int(d[4] * float32(height))
+			right := int(d[5] * float32(width))
+			bottom := int(d[6] * float32(height))
+			if right <= left || bottom <= top {
+				continue
+			}
+			dets = append(dets, dnnDetection{
+				rect: bridge.Rect{
+					X:      left,
+					Y:      top,
+					Width:  right - left,
+					Height: bottom - top,
+				},
+				class: int(d[1]),
+				score: score,
+			})
+		}
+	}
+	return dets, nil
+}
+
+// decodeYOLO decodes YOLO style outputs. Each output has [N, 5+C] shape, and
+// each detection is [center x, center y, width, height, objectness, scores of
+// C classes] with coordinates normalized by the image size. The score of the
+// detection is the highest class score.
+func decodeYOLO(ts []bridge.Tensor, width, height int,
+	threshold float32) ([]dnnDetection, error) {
+	dets := []dnnDetection{}
+	for _, t := range ts {
+		if len(t.Shape) != 2 || t.Shape[1] <= 5 {
+			return nil, fmt.Errorf("output of YOLO must be [N, 5+C]: %v",
+				t.Shape)
+		}
+		cols := t.Shape[1]
+		for i := 0; i+cols <= len(t.Data); i += cols {
+			d := t.Data[i : i+cols]
+			class := 0
+			score := d[5]
+			for c, s := range d[5:] {
+				if s > score {
+					class, score = c, s
+				}
+			}
+			if score < threshold {
+				continue
+			}
+			w := int(d[2] * float32(width))
+			h := int(d[3] * float32(height))
+			if w <= 0 || h <= 0 {
+				continue
+			}
+			dets = append(dets, dnnDetection{
+				rect: bridge.Rect{
+					X:      int(d[0]*float32(width)) - w/2,
+					Y:      int(d[1]*float32(height)) - h/2,
+					Width:  w,
+					Height: h,
+				},
+				class: class,
+				score: score,
+			})
+		}
+	}
+	return dets, nil
+}
+
+// suppressDetections applies non maximum suppression to detections of each
+// class. The result is ordered by class ID and descending score.
+func suppressDetections(dets []dnnDetection, params dnnDetectParams) []dnnDetection {
+	byClass := map[int][]dnnDetection{}
+	classes := []int{}
+	for _, d := range dets {
+		if _, ok := byClass[d.class]; !ok {
+			classes = append(classes, d.class)
+		}
+		byClass[d.class] = append(byClass[d.class], d)
+	}
+	sort.Ints(classes)
+
+	ret := []dnnDetection{}
+	for _, c := range classes {
+		cds := byClass[c]
+		rects := make([]bridge.Rect, len(cds))
+		scores := make([]float32, len(cds))
+		for i, d := range cds {
+			rects[i] = d.rect
+			scores[i] = d.score
+		}
+		for _, idx := range bridge.NMSBoxes(rects, scores,
+			params.confidenceThreshold, params.nmsThreshold) {
+			ret = append(ret, cds[idx])
+		}
+	}
+	return ret
+}
+
+// DnnDetect runs forward pass of the network with the image, and decodes the
+// outputs to detected objects. Detections of which score is lower than
+// confidence_threshold are dropped, and non maximum suppression is applied
+// for each class. The result is an array of maps which have "x", "y",
+// "width", "height", "class", "label" and "score", so the result can be used
+// with opencv_draw_rects. "label" is an empty string when labels of the state
+// are not set or the class ID is out of them.
+//
+// netName: dnnNet state name.
+//
+// img: target image as RawData map structure.
+//
+// options: [optional] blob and detection parameters which overwrite the
+// default values of the state. See NewDnnNet for available parameters.
+//
+// Coordinates are mapped to the input image by the normalized outputs, so
+// "crop" blob parameter is not supported for detection.
+func DnnDetect(ctx *core.Context, netName string, img data.Map,
+	options ...data.Map) (data.Array, error) {
+	if len(options) > 1 {
+		return nil, fmt.Errorf("detection options must be only one map")
+	}
+	net, err := lookupDnnNet(ctx, netName)
+	if err != nil {
+		return nil, err
+	}
+	blobParams := net.params
+	detectParams := net.detectParams
+	if len(options) > 0 {
+		if blobParams, err = parseBlobParams(blobParams, options[0]); err != nil {
+			return nil, err
+		}
+		if detectParams, err = parseDnnDetectParams(detectParams,
+			options[0]); err != nil {
+			return nil, err
+		}
+	}
+	if blobParams.Crop {
+		return nil, fmt.Errorf("crop is not supported for detection")
+	}
+
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	mat, err := raw.ToMatVec3b()
+	if err != nil {
+		return nil, err
+	}
+	defer mat.Delete()
+
+	ts, err := net.forwardOutputs(mat, blobParams)
+	if err != nil {
+		return nil, err
+	}
+	var dets []dnnDetection
+	switch detectParams.decoder {
+	case "yolo":
+		dets, err = decodeYOLO(ts, mat.Cols(), mat.Rows(),
+			detectParams.confidenceThreshold)
+	default:
+		dets, err = decodeSSD(ts, mat.Cols(), mat.Rows(),
+			detectParams.confidenceThreshold)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dets = suppressDetections(dets, detectParams)
+	ret := make(data.Array, len(dets))
+	for i, d := range dets {
+		ret[i] = data.Map{
+			"x":      data.Int(d.rect.X),
+			"y":      data.Int(d.rect.Y),
+			"width":  data.Int(d.rect.Width),
+			"height": data.Int(d.rect.Height),
+			"class":  data.Int(d.class),
+			"label":  data.String(net.label(d.class)),
+			"score":  data.Float(d.score),
+		}
+	}
+	return ret, nil
+}
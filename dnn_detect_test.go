@@ -0,0 +1,139 @@
+//go:build dnn
+// +build dnn
+
+package opencv
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"testing"
+)
+
+func TestParseDnnDetectParams(t *testing.T) {
+	Convey("Given default dnn detection parameters", t, func() {
+		params := defaultDnnDetectParams()
+		Convey("When parse a map with full parameters", func() {
+			m := data.Map{
+				"decoder":              data.String("yolo"),
+				"confidence_threshold": data.Float(0.25),
+				"nms_threshold":        data.Float(0.5),
+			}
+			p, err := parseDnnDetectParams(params, m)
+			Convey("Then all parameters should be overwritten", func() {
+				So(err, ShouldBeNil)
+				So(p, ShouldResemble, dnnDetectParams{
+					decoder:             "yolo",
+					confidenceThreshold: 0.25,
+					nmsThreshold:        0.5,
+				})
+			})
+		})
+
+		Convey("When parse a map with invalid parameters", func() {
+			testMap := data.Map{
+				"decoder":              data.String("faster_rcnn"),
+				"confidence_threshold": data.Float(1.5),
+				"nms_threshold":        data.String("a"),
+			}
+			for k, v := range testMap {
+				v := v
+				Convey("Then an error should be occurred with "+k, func() {
+					_, err := parseDnnDetectParams(params, data.Map{k: v})
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestDecodeSSD(t *testing.T) {
+	Convey("Given a SSD output which has 2 detections", t, func() {
+		ts := []bridge.Tensor{{
+			Data: []float32{
+				0, 15, 0.9, 0.1, 0.2, 0.5, 0.8,
+				0, 7, 0.3, 0.0, 0.0, 0.5, 0.5,
+			},
+			Shape: []int{1, 1, 2, 7},
+		}}
+		Convey("When decode it with 0.5 threshold", func() {
+			dets, err := decodeSSD(ts, 100, 200, 0.5)
+			Convey("Then the detection over the threshold should be returned", func() {
+				So(err, ShouldBeNil)
+				So(len(dets), ShouldEqual, 1)
+				So(dets[0].class, ShouldEqual, 15)
+				So(dets[0].score, ShouldAlmostEqual, 0.9, 0.0001)
+				So(dets[0].rect, ShouldResemble, bridge.Rect{
+					X: 10, Y: 40, Width: 40, Height: 120})
+			})
+		})
+	})
+
+	Convey("Given an output which is not SSD layout", t, func() {
+		ts := []bridge.Tensor{{
+			Data:  []float32{1, 2, 3, 4, 5, 6},
+			Shape: []int{1, 6},
+		}}
+		Convey("When decode it", func() {
+			_, err := decodeSSD(ts, 100, 100, 0.5)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestDecodeYOLO(t *testing.T) {
+	Convey("Given a YOLO output which has 2 detections of 2 classes", t, func() {
+		ts := []bridge.Tensor{{
+			Data: []float32{
+				0.5, 0.5, 0.2, 0.4, 0.9, 0.1, 0.8,
+				0.5, 0.5, 0.2, 0.4, 0.2, 0.1, 0.2,
+			},
+			Shape: []int{2, 7},
+		}}
+		Convey("When decode it with 0.5 threshold", func() {
+			dets, err := decodeYOLO(ts, 100, 100, 0.5)
+			Convey("Then the detection of the best class should be returned", func() {
+				So(err, ShouldBeNil)
+				So(len(dets), ShouldEqual, 1)
+				So(dets[0].class, ShouldEqual, 1)
+				So(dets[0].score, ShouldAlmostEqual, 0.8, 0.0001)
+				So(dets[0].rect, ShouldResemble, bridge.Rect{
+					X: 40, Y: 30, Width: 20, Height: 40})
+			})
+		})
+	})
+
+	Convey("Given an output which is not YOLO layout", t, func() {
+		ts := []bridge.Tensor{{
+			Data:  []float32{1, 2, 3, 4, 5},
+			Shape: []int{1, 5},
+		}}
+		Convey("When decode it", func() {
+			_, err := decodeYOLO(ts, 100, 100, 0.5)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestSuppressDetections(t *testing.T) {
+	Convey("Given overlapped detections of 2 classes", t, func() {
+		dets := []dnnDetection{
+			{rect: bridge.Rect{X: 0, Y: 0, Width: 10, Height: 10}, class: 1, score: 0.6},
+			{rect: bridge.Rect{X: 1, Y: 1, Width: 10, Height: 10}, class: 1, score: 0.9},
+			{rect: bridge.Rect{X: 0, Y: 0, Width: 10, Height: 10}, class: 0, score: 0.7},
+		}
+		Convey("When suppress them", func() {
+			ret := suppressDetections(dets, defaultDnnDetectParams())
+			Convey("Then the best detection of each class should be kept", func() {
+				So(len(ret), ShouldEqual, 2)
+				So(ret[0].class, ShouldEqual, 0)
+				So(ret[1].class, ShouldEqual, 1)
+				So(ret[1].score, ShouldAlmostEqual, 0.9, 0.0001)
+			})
+		})
+	})
+}
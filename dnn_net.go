@@ -0,0 +1,358 @@
+//go:build dnn
+// +build dnn
+
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	modelPath     = data.MustCompilePath("model")
+	netConfigPath = data.MustCompilePath("config")
+	frameworkPath = data.MustCompilePath("framework")
+	sizePath      = data.MustCompilePath("size")
+	meanPath      = data.MustCompilePath("mean")
+	swapRBPath    = data.MustCompilePath("swap_rb")
+	cropPath      = data.MustCompilePath("crop")
+	labelsPath    = data.MustCompilePath("labels")
+)
+
+// NewDnnNet returns dnnNet state. The network runs on CPU with OpenCV
+// backend.
+//
+// model: [required] The file path of trained weights,
+// e.g. "MobileNetSSD_deploy.caffemodel".
+//
+// config: The file path of network configuration, e.g. Caffe's prototxt or
+// Darknet's cfg. Required for "caffe" and "darknet".
+//
+// framework: "caffe", "tensorflow", "onnx" or "darknet". If not set then
+// decided by the extension of model (".caffemodel", ".pb", ".onnx" and
+// ".weights").
+//
+// scale: A multiplier for image values, default is 1.
+//
+// size: Spatial size of the input blob as a map which has "width" and
+// "height" (e.g. {"width":300, "height":300}), default is the size of the
+// input image.
+//
+// mean: An array of mean values which are subtracted from channels (e.g.
+// [104, 117, 123]), default is [0, 0, 0].
+//
+// swap_rb: If set `true` then the first and the last channels are swapped,
+// default is false.
+//
+// crop: If set `true` then the image is cropped after resize with keeping
+// aspect ratio, default is false.
+//
+// labels: [optional] The file path of class labels, one label per line. The
+// label of class ID N is written in the (N+1)-th line, e.g. "background" is
+// written in the first line for Caffe's MobileNet-SSD.
+//
+// decoder: "ssd" or "yolo", the output layout used by opencv_dnn_detect,
+// default is "ssd".
+//
+// confidence_threshold: Detections of which score is lower than this value
+// are dropped by opencv_dnn_detect, default is 0.5.
+//
+// nms_threshold: IoU threshold of non maximum suppression in
+// opencv_dnn_detect, default is 0.4.
+//
+// top_k: The number of classes returned by opencv_dnn_classify, default is 5.
+//
+// softmax: If set `true` then opencv_dnn_classify normalizes scores by
+// softmax, default is false. Set this when the network outputs raw logits.
+//
+// These blob parameters are used as default values of opencv_dnn_forward,
+// opencv_dnn_detect and opencv_dnn_classify, and detection and classification
+// parameters are used as default values of each UDF.
+func NewDnnNet(ctx *core.Context, params data.Map) (core.SharedState, error) {
+	var model string
+	if m, err := params.Get(modelPath); err != nil {
+		return nil, err
+	} else if model, err = data.AsString(m); err != nil {
+		return nil, err
+	}
+
+	config := ""
+	if c, err := params.Get(netConfigPath); err == nil {
+		if config, err = data.AsString(c); err != nil {
+			return nil, err
+		}
+	}
+
+	framework := ""
+	if f, err := params.Get(frameworkPath); err == nil {
+		if framework, err = data.AsString(f); err != nil {
+			return nil, err
+		}
+	} else if framework, err = detectDnnFramework(model); err != nil {
+		return nil, err
+	}
+	switch framework {
+	case "caffe", "darknet":
+		if config == "" {
+			return nil, fmt.Errorf("'%v' model requires config", framework)
+		}
+	case "tensorflow", "onnx":
+	default:
+		return nil, fmt.Errorf("'%v' framework is not supported", framework)
+	}
+
+	blobParams, err := parseBlobParams(bridge.DefaultBlobParams(), params)
+	if err != nil {
+		return nil, err
+	}
+	detectParams, err := parseDnnDetectParams(defaultDnnDetectParams(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	classifyParams, err := parseDnnClassifyParams(defaultDnnClassifyParams(),
+		params)
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []string
+	if l, err := params.Get(labelsPath); err == nil {
+		labelsFile, err := data.AsString(l)
+		if err != nil {
+			return nil, err
+		}
+		if labels, err = loadLabels(labelsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	net := bridge.ReadDnnNet(framework, model, config)
+	if net.Empty() {
+		net.Delete()
+		return nil, fmt.Errorf("cannot load the model '%v'", model)
+	}
+
+	return &dnnNet{
+		net:            net,
+		params:         blobParams,
+		detectParams:   detectParams,
+		classifyParams: classifyParams,
+		labels:         labels,
+	}, nil
+}
+
+// loadLabels reads class labels from the file, one label per line. Trailing
+// empty lines are ignored.
+func loadLabels(fileName string) ([]string, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(b), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, "\r")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, nil
+}
+
+func detectDnnFramework(model string) (string, error) {
+	switch filepath.Ext(model) {
+	case ".caffemodel":
+		return "caffe", nil
+	case ".pb":
+		return "tensorflow", nil
+	case ".onnx":
+		return "onnx", nil
+	case ".weights":
+		return "darknet", nil
+	default:
+		return "", fmt.Errorf("cannot detect the framework of '%v'", model)
+	}
+}
+
+type dnnNet struct {
+	// mu is used because cv::dnn::Net cannot run forward passes concurrently.
+	mu             sync.Mutex
+	net            bridge.DnnNet
+	params         bridge.BlobParams
+	detectParams   dnnDetectParams
+	classifyParams dnnClassifyParams
+	labels         []string
+}
+
+func (n *dnnNet) Terminate(ctx *core.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.net.Delete()
+	return nil
+}
+
+func (n *dnnNet) forward(img bridge.MatVec3b, params bridge.BlobParams) (
+	bridge.Tensor, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	t, ok := n.net.Forward(img, params)
+	if !ok {
+		return bridge.Tensor{}, fmt.Errorf("cannot run forward pass")
+	}
+	return t, nil
+}
+
+func (n *dnnNet) forwardOutputs(img bridge.MatVec3b,
+	params bridge.BlobParams) ([]bridge.Tensor, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ts, ok := n.net.ForwardOutputs(img, params)
+	if !ok {
+		return nil, fmt.Errorf("cannot run forward pass")
+	}
+	return ts, nil
+}
+
+// label returns the label of the class ID, or an empty string when the label
+// is not defined.
+func (n *dnnNet) label(class int) string {
+	if class < 0 || class >= len(n.labels) {
+		return ""
+	}
+	return n.labels[class]
+}
+
+func lookupDnnNet(ctx *core.Context, name string) (*dnnNet, error) {
+	st, err := ctx.SharedStates.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := st.(*dnnNet); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("state '%v' cannot be converted to dnn_net.state",
+		name)
+}
+
+// parseBlobParams returns blob parameters which are overwritten by values in
+// the map.
+func parseBlobParams(params bridge.BlobParams, m data.Map) (bridge.BlobParams,
+	error) {
+	if s, err := m.Get(scalePath); err == nil {
+		if params.Scale, err = data.ToFloat(s); err != nil {
+			return params, err
+		}
+	}
+
+	if s, err := m.Get(sizePath); err == nil {
+		if params.Size, err = convertToBridgeSize(s); err != nil {
+			return params, err
+		}
+	}
+
+	if mv, err := m.Get(meanPath); err == nil {
+		mean, err := data.AsArray(mv)
+		if err != nil {
+			return params, err
+		}
+		if len(mean) > len(params.Mean) {
+			return params, fmt.Errorf("mean must have at most %v values: %v",
+				len(params.Mean), len(mean))
+		}
+		params.Mean = [3]float64{}
+		for i, v := range mean {
+			if params.Mean[i], err = data.ToFloat(v); err != nil {
+				return params, err
+			}
+		}
+	}
+
+	if s, err := m.Get(swapRBPath); err == nil {
+		if params.SwapRB, err = data.AsBool(s); err != nil {
+			return params, err
+		}
+	}
+
+	if c, err := m.Get(cropPath); err == nil {
+		if params.Crop, err = data.AsBool(c); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+// DnnForward runs forward pass of the network with the image, and returns the
+// output tensor as nested arrays. The nesting depth of the arrays is the same
+// as dimensions of the tensor, e.g. an output of which shape is [1, 1, 100, 7]
+// is returned as 4-nested arrays.
+//
+// netName: dnnNet state name.
+//
+// img: target image as RawData map structure.
+//
+// options: [optional] blob parameters which overwrite the default values of
+// the state. See NewDnnNet for available parameters.
+func DnnForward(ctx *core.Context, netName string, img data.Map,
+	options ...data.Map) (data.Array, error) {
+	t, err := dnnForward(ctx, netName, img, options)
+	if err != nil {
+		return nil, err
+	}
+	return tensorToArray(t.Data, t.Shape), nil
+}
+
+func dnnForward(ctx *core.Context, netName string, img data.Map,
+	options []data.Map) (bridge.Tensor, error) {
+	if len(options) > 1 {
+		return bridge.Tensor{}, fmt.Errorf("blob options must be only one map")
+	}
+	net, err := lookupDnnNet(ctx, netName)
+	if err != nil {
+		return bridge.Tensor{}, err
+	}
+	params := net.params
+	if len(options) > 0 {
+		if params, err = parseBlobParams(params, options[0]); err != nil {
+			return bridge.Tensor{}, err
+		}
+	}
+
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return bridge.Tensor{}, err
+	}
+	mat, err := raw.ToMatVec3b()
+	if err != nil {
+		return bridge.Tensor{}, err
+	}
+	defer mat.Delete()
+	return net.forward(mat, params)
+}
+
+// tensorToArray converts values in row-major order to nested arrays.
+func tensorToArray(values []float32, shape []int) data.Array {
+	if len(shape) <= 1 {
+		ret := make(data.Array, len(values))
+		for i, v := range values {
+			ret[i] = data.Float(v)
+		}
+		return ret
+	}
+	n := shape[0]
+	stride := 0
+	if n > 0 {
+		stride = len(values) / n
+	}
+	ret := make(data.Array, n)
+	for i := 0; i < n; i++ {
+		ret[i] = tensorToArray(values[i*stride:(i+1)*stride], shape[1:])
+	}
+	return ret
+}
@@ -0,0 +1,169 @@
+//go:build dnn
+// +build dnn
+
+package opencv
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNewDnnNet(t *testing.T) {
+	Convey("Given a SensorBee's core.Context", t, func() {
+		ctx := &core.Context{}
+		Convey("When create state with empty map", func() {
+			_, err := NewDnnNet(ctx, data.Map{})
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When create state with unknown model extension", func() {
+			params := data.Map{
+				"model": data.String("model.bin"),
+			}
+			_, err := NewDnnNet(ctx, params)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When create state with not supported framework", func() {
+			params := data.Map{
+				"model":     data.String("model.bin"),
+				"framework": data.String("torch7"),
+			}
+			_, err := NewDnnNet(ctx, params)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When create state of caffe model without config", func() {
+			params := data.Map{
+				"model": data.String("model.caffemodel"),
+			}
+			_, err := NewDnnNet(ctx, params)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When create state with not exist labels file", func() {
+			params := data.Map{
+				"model":  data.String("model.onnx"),
+				"labels": data.String("not_exist_labels.txt"),
+			}
+			_, err := NewDnnNet(ctx, params)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When create state with invalid decoder", func() {
+			params := data.Map{
+				"model":   data.String("model.onnx"),
+				"decoder": data.String("rcnn"),
+			}
+			_, err := NewDnnNet(ctx, params)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When create state with not exist model", func() {
+			params := data.Map{
+				"model": data.String("not_exist.onnx"),
+			}
+			_, err := NewDnnNet(ctx, params)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestParseBlobParams(t *testing.T) {
+	Convey("Given default blob parameters", t, func() {
+		params := bridge.DefaultBlobParams()
+		Convey("When parse a map with full parameters", func() {
+			m := data.Map{
+				"scale": data.Float(0.007843),
+				"size": data.Map{
+					"width":  data.Int(300),
+					"height": data.Int(300),
+				},
+				"mean":    data.Array{data.Float(127.5), data.Int(127), data.Int(126)},
+				"swap_rb": data.True,
+				"crop":    data.True,
+			}
+			p, err := parseBlobParams(params, m)
+			Convey("Then all parameters should be overwritten", func() {
+				So(err, ShouldBeNil)
+				So(p, ShouldResemble, bridge.BlobParams{
+					Scale:  0.007843,
+					Size:   bridge.Size{Width: 300, Height: 300},
+					Mean:   [3]float64{127.5, 127, 126},
+					SwapRB: true,
+					Crop:   true,
+				})
+			})
+		})
+
+		Convey("When parse a map with invalid parameters", func() {
+			testMap := data.Map{
+				"scale": data.String("a"),
+				"size":  data.Int(300),
+				"mean": data.Array{data.Int(1), data.Int(2), data.Int(3),
+					data.Int(4)},
+				"swap_rb": data.String("true"),
+				"crop":    data.Int(1),
+			}
+			for k, v := range testMap {
+				v := v
+				Convey("Then an error should be occurred with "+k, func() {
+					_, err := parseBlobParams(params, data.Map{k: v})
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+	})
+}
+
+func TestTensorToArray(t *testing.T) {
+	Convey("Given a tensor of which shape is [1, 2, 3]", t, func() {
+		values := []float32{1, 2, 3, 4, 5, 6}
+		shape := []int{1, 2, 3}
+		Convey("When convert it to array", func() {
+			arr := tensorToArray(values, shape)
+			Convey("Then the array should be nested by the shape", func() {
+				So(arr, ShouldResemble, data.Array{
+					data.Array{
+						data.Array{data.Float(1), data.Float(2), data.Float(3)},
+						data.Array{data.Float(4), data.Float(5), data.Float(6)},
+					},
+				})
+			})
+		})
+	})
+}
+
+func TestLoadLabels(t *testing.T) {
+	Convey("Given a labels file which has CRLF and a trailing empty line", t, func() {
+		f, err := ioutil.TempFile("", "labels")
+		So(err, ShouldBeNil)
+		_, err = f.WriteString("background\r\nperson\r\n\r\ncar\r\n\r\n")
+		So(err, ShouldBeNil)
+		f.Close()
+		Reset(func() {
+			os.Remove(f.Name())
+		})
+		Convey("When load the file", func() {
+			labels, err := loadLabels(f.Name())
+			Convey("Then labels should be indexed by line", func() {
+				So(err, ShouldBeNil)
+				So(labels, ShouldResemble, []string{"background", "person", "",
+					"car"})
+			})
+		})
+	})
+}
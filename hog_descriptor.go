@@ -0,0 +1,195 @@
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+)
+
+var (
+	hitThresholdPath   = data.MustCompilePath("hit_threshold")
+	winStridePath      = data.MustCompilePath("win_stride")
+	paddingPath        = data.MustCompilePath("padding")
+	scalePath          = data.MustCompilePath("scale")
+	finalThresholdPath = data.MustCompilePath("final_threshold")
+)
+
+// NewHOGDescriptor returns hogDescriptor state.
+//
+// file: [optional] HOG descriptor configuration file path which includes SVM
+// detector, e.g. a file saved by `cv::HOGDescriptor::save`. If not set then
+// OpenCV's default people detector is used.
+//
+// hit_threshold: Threshold for the distance between features and SVM
+// classifying plane, default is 0.
+//
+// win_stride: Window stride as a map which has "width" and "height"
+// (e.g. {"width":8, "height":8}), default is OpenCV's default. Zero size means
+// OpenCV's default, and only one of width and height cannot be zero.
+//
+// padding: Padding as the same structure of win_stride, default is OpenCV's
+// default.
+//
+// scale: Coefficient of the detection window increase, default is 1.05.
+//
+// final_threshold: Threshold to group detected rectangles, default is 2.
+//
+// These detection parameters are used as default values of
+// opencv_hog_detect_multi_scale.
+func NewHOGDescriptor(ctx *core.Context, params data.Map) (core.SharedState,
+	error) {
+	filePath := ""
+	if fp, err := params.Get(configFilePath); err == nil {
+		if filePath, err = data.AsString(fp); err != nil {
+			return nil, err
+		}
+	}
+
+	detectParams, err := parseHOGDetectParams(bridge.DefaultHOGDetectParams(),
+		params)
+	if err != nil {
+		return nil, err
+	}
+
+	hog := bridge.NewHOGDescriptor()
+	if filePath == "" {
+		hog.SetDefaultPeopleDetector()
+	} else if !hog.Load(filePath) {
+		hog.Delete()
+		return nil, fmt.Errorf("cannot load the file '%v'", filePath)
+	}
+
+	return &hogDescriptor{
+		descriptor: hog,
+		params:     detectParams,
+	}, nil
+}
+
+type hogDescriptor struct {
+	descriptor bridge.HOGDescriptor
+	params     bridge.HOGDetectParams
+}
+
+func (h *hogDescriptor) Terminate(ctx *core.Context) error {
+	h.descriptor.Delete()
+	return nil
+}
+
+func lookupHOGDescriptor(ctx *core.Context, name string) (*hogDescriptor,
+	error) {
+	st, err := ctx.SharedStates.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := st.(*hogDescriptor); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("state '%v' cannot be converted to hog_descriptor.state",
+		name)
+}
+
+// parseHOGDetectParams returns detection parameters which are overwritten by
+// values in the map.
+func parseHOGDetectParams(params bridge.HOGDetectParams, m data.Map) (
+	bridge.HOGDetectParams, error) {
+	if ht, err := m.Get(hitThresholdPath); err == nil {
+		if params.HitThreshold, err = data.ToFloat(ht); err != nil {
+			return params, err
+		}
+	}
+
+	if ws, err := m.Get(winStridePath); err == nil {
+		if params.WinStride, err = convertToBridgeSize(ws); err != nil {
+			return params, err
+		}
+		// zero size means the default stride, otherwise OpenCV divides by it
+		if (params.WinStride.Width == 0) != (params.WinStride.Height == 0) {
+			return params, fmt.Errorf(
+				"win_stride must not have only one zero dimension: %vx%v",
+				params.WinStride.Width, params.WinStride.Height)
+		}
+	}
+
+	if p, err := m.Get(paddingPath); err == nil {
+		if params.Padding, err = convertToBridgeSize(p); err != nil {
+			return params, err
+		}
+	}
+
+	if s, err := m.Get(scalePath); err == nil {
+		if params.Scale, err = data.ToFloat(s); err != nil {
+			return params, err
+		}
+		if params.Scale <= 1 {
+			return params, fmt.Errorf("scale must be greater than 1: %v",
+				params.Scale)
+		}
+	}
+
+	if ft, err := m.Get(finalThresholdPath); err == nil {
+		if params.FinalThreshold, err = data.ToFloat(ft); err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+// HOGDetectMultiScale detects objects (e.g. people) on the image. The result
+// has the same structure as DetectMultiScale, and each rectangle also has
+// "weight", so the result can be used with opencv_draw_rects.
+//
+// descriptorName: hogDescriptor state name.
+//
+// img: target image as RawData map structure.
+//
+// options: [optional] detection parameters which overwrite the default values
+// of the state. See NewHOGDescriptor for available parameters.
+//
+// An error is returned when OpenCV fails to detect, e.g. the size of the SVM
+// detector does not match the window.
+func HOGDetectMultiScale(ctx *core.Context, descriptorName string, img data.Map,
+	options ...data.Map) (data.Array, error) {
+	if len(options) > 1 {
+		return nil, fmt.Errorf("detection options must be only one map")
+	}
+	descriptor, err := lookupHOGDescriptor(ctx, descriptorName)
+	if err != nil {
+		return nil, err
+	}
+	params := descriptor.params
+	if len(options) > 0 {
+		if params, err = parseHOGDetectParams(params, options[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	mat, err := raw.ToMatVec3b()
+	if err != nil {
+		return nil, err
+	}
+	defer mat.Delete()
+
+	rects, ok := descriptor.descriptor.DetectMultiScale(mat, params)
+	if !ok {
+		return nil, fmt.Errorf("cannot detect with the state '%v'",
+			descriptorName)
+	}
+	ret := make(data.Array, len(rects))
+	for i, r := range rects {
+		rect := data.Map{
+			"x":      data.Int(r.X),
+			"y":      data.Int(r.Y),
+			"width":  data.Int(r.Width),
+			"height": data.Int(r.Height),
+			"weight": data.Float(r.Weight),
+		}
+		ret[i] = rect
+	}
+	return ret, nil
+}
@@ -0,0 +1,155 @@
+package opencv
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"testing"
+)
+
+func TestNewHOGDescriptor(t *testing.T) {
+	Convey("Given a SensorBee's core.Context", t, func() {
+		ctx := &core.Context{}
+		Convey("When create state with empty map", func() {
+			st, err := NewHOGDescriptor(ctx, data.Map{})
+			So(err, ShouldBeNil)
+			Reset(func() {
+				st.Terminate(ctx)
+			})
+			Convey("Then state should be created with default parameters", func() {
+				hog, ok := st.(*hogDescriptor)
+				So(ok, ShouldBeTrue)
+				So(hog.params, ShouldResemble, bridge.DefaultHOGDetectParams())
+			})
+		})
+		Convey("When create state with not exist file name", func() {
+			params := data.Map{
+				"file": data.String("not_exist_file"),
+			}
+			_, err := NewHOGDescriptor(ctx, params)
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When create state with full parameters", func() {
+			params := data.Map{
+				"hit_threshold": data.Float(0.5),
+				"win_stride": data.Map{
+					"width":  data.Int(8),
+					"height": data.Int(8),
+				},
+				"padding": data.Map{
+					"width":  data.Int(16),
+					"height": data.Int(16),
+				},
+				"scale":           data.Float(1.1),
+				"final_threshold": data.Float(1),
+			}
+			st, err := NewHOGDescriptor(ctx, params)
+			So(err, ShouldBeNil)
+			Reset(func() {
+				st.Terminate(ctx)
+			})
+			Convey("Then state should have the parameters", func() {
+				hog, ok := st.(*hogDescriptor)
+				So(ok, ShouldBeTrue)
+				So(hog.params, ShouldResemble, bridge.HOGDetectParams{
+					HitThreshold:   0.5,
+					WinStride:      bridge.Size{Width: 8, Height: 8},
+					Padding:        bridge.Size{Width: 16, Height: 16},
+					Scale:          1.1,
+					FinalThreshold: 1,
+				})
+			})
+		})
+		Convey("When create state with invalid parameters", func() {
+			testMap := data.Map{
+				"hit_threshold":   data.String("a"),
+				"win_stride":      data.Int(8),
+				"padding":         data.Map{"width": data.Int(8)},
+				"scale":           data.Float(0.9),
+				"final_threshold": data.String("b"),
+			}
+			for k, v := range testMap {
+				v := v
+				Convey("Then should return an error with "+k, func() {
+					_, err := NewHOGDescriptor(ctx, data.Map{k: v})
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+		Convey("When create state with win_stride which has one zero dimension", func() {
+			for _, s := range [][2]int64{{8, 0}, {0, 8}} {
+				params := data.Map{
+					"win_stride": data.Map{
+						"width":  data.Int(s[0]),
+						"height": data.Int(s[1]),
+					},
+				}
+				msg := fmt.Sprintf("%vx%v", s[0], s[1])
+				Convey("Then should return an error with "+msg, func() {
+					_, err := NewHOGDescriptor(ctx, params)
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+		Convey("When create state with zero win_stride", func() {
+			params := data.Map{
+				"win_stride": data.Map{
+					"width":  data.Int(0),
+					"height": data.Int(0),
+				},
+			}
+			st, err := NewHOGDescriptor(ctx, params)
+			So(err, ShouldBeNil)
+			Reset(func() {
+				st.Terminate(ctx)
+			})
+			Convey("Then state should use the default stride", func() {
+				hog, ok := st.(*hogDescriptor)
+				So(ok, ShouldBeTrue)
+				So(hog.params.WinStride, ShouldResemble, bridge.Size{})
+			})
+		})
+	})
+}
+
+func TestHOGDetectMultiScale(t *testing.T) {
+	Convey("Given a context which has hog descriptor state", t, func() {
+		ctx := core.NewContext(&core.ContextConfig{})
+		st, err := NewHOGDescriptor(ctx, data.Map{})
+		So(err, ShouldBeNil)
+		So(ctx.SharedStates.Add("hog", "opencv_hog_descriptor", st), ShouldBeNil)
+		Reset(func() {
+			ctx.SharedStates.Remove("hog")
+			st.Terminate(ctx)
+		})
+		Convey("When detect on a blank image", func() {
+			raw := RawData{
+				Format: TypeCVMAT,
+				Width:  64,
+				Height: 128,
+				Data:   make([]byte, 64*128*3),
+			}
+			rects, err := HOGDetectMultiScale(ctx, "hog", raw.ConvertToDataMap())
+			Convey("Then no rectangle should be detected", func() {
+				So(err, ShouldBeNil)
+				So(rects, ShouldBeEmpty)
+			})
+		})
+		Convey("When detect with not exist state name", func() {
+			raw := RawData{
+				Format: TypeCVMAT,
+				Width:  64,
+				Height: 128,
+				Data:   make([]byte, 64*128*3),
+			}
+			_, err := HOGDetectMultiScale(ctx, "not_exist", raw.ConvertToDataMap())
+			Convey("Then should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
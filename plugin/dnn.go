@@ -0,0 +1,21 @@
+//go:build dnn
+// +build dnn
+
+package plugin
+
+import (
+	"gopkg.in/sensorbee/opencv.v0"
+	"gopkg.in/sensorbee/sensorbee.v0/bql/udf"
+)
+
+// initialize DNN components, which are built only with "dnn" build tag.
+func init() {
+	udf.MustRegisterGlobalUDSCreator("opencv_dnn_net",
+		udf.UDSCreatorFunc(opencv.NewDnnNet))
+	udf.MustRegisterGlobalUDF("opencv_dnn_forward",
+		udf.MustConvertGeneric(opencv.DnnForward))
+	udf.MustRegisterGlobalUDF("opencv_dnn_detect",
+		udf.MustConvertGeneric(opencv.DnnDetect))
+	udf.MustRegisterGlobalUDF("opencv_dnn_classify",
+		udf.MustConvertGeneric(opencv.DnnClassify))
+}
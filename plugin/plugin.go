@@ -18,15 +18,55 @@ func init() {
 		&opencv.FromURICreator{})
 	bql.MustRegisterGlobalSourceCreator("opencv_capture_from_device",
 		&opencv.FromDeviceCreator{})
+	bql.MustRegisterGlobalSourceCreator("opencv_capture_from_files",
+		&opencv.FromFilesCreator{})
+
+	// video writer
+	bql.MustRegisterGlobalSinkCreator("opencv_video_writer",
+		&opencv.VideoWriterCreator{})
+
+	// image encoding
+	udf.MustRegisterGlobalUDF("opencv_encode",
+		udf.MustConvertGeneric(opencv.EncodeImage))
+	udf.MustRegisterGlobalUDF("opencv_decode",
+		udf.MustConvertGeneric(opencv.DecodeImage))
+	udf.MustRegisterGlobalUDF("opencv_from_image_blob",
+		udf.MustConvertGeneric(opencv.FromImageBlob))
 
 	// cascade classifier
 	udf.MustRegisterGlobalUDSCreator("opencv_cascade_classifier",
 		udf.UDSCreatorFunc(opencv.NewCascadeClassifier))
 	udf.MustRegisterGlobalUDF("opencv_detect_multi_scale",
 		udf.MustConvertGeneric(opencv.DetectMultiScale))
+	udf.MustRegisterGlobalUDF("opencv_detect_multi_scale_with_weights",
+		udf.MustConvertGeneric(opencv.DetectMultiScaleWithWeights))
 	udf.MustRegisterGlobalUDF("opencv_draw_rects",
 		udf.MustConvertGeneric(opencv.DrawRectsToImage))
 
+	// crop
+	udf.MustRegisterGlobalUDF("opencv_crop",
+		udf.MustConvertGeneric(opencv.Crop))
+	udf.MustRegisterGlobalUDF("opencv_crop_all",
+		udf.MustConvertGeneric(opencv.CropAll))
+
+	// resize
+	udf.MustRegisterGlobalUDF("opencv_resize",
+		udf.MustConvertGeneric(opencv.Resize))
+	udf.MustRegisterGlobalUDF("opencv_scale",
+		udf.MustConvertGeneric(opencv.Scale))
+	udf.MustRegisterGlobalUDF("opencv_letterbox",
+		udf.MustConvertGeneric(opencv.Letterbox))
+
+	// color
+	udf.MustRegisterGlobalUDF("opencv_cvt_color",
+		udf.MustConvertGeneric(opencv.CvtColor))
+
+	// HOG descriptor
+	udf.MustRegisterGlobalUDSCreator("opencv_hog_descriptor",
+		udf.UDSCreatorFunc(opencv.NewHOGDescriptor))
+	udf.MustRegisterGlobalUDF("opencv_hog_detect_multi_scale",
+		udf.MustConvertGeneric(opencv.HOGDetectMultiScale))
+
 	// mount image
 	udf.MustRegisterGlobalUDSCreator("opencv_shared_image",
 		udf.UDSCreatorFunc(opencv.NewSharedImage))
@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
+	"image/png"
 
 	"gopkg.in/sensorbee/opencv.v0/bridge"
 	"gopkg.in/sensorbee/sensorbee.v0/data"
 )
 
 var (
-	imagePath = data.MustCompilePath("image")
+	imagePath       = data.MustCompilePath("image")
+	modePath        = data.MustCompilePath("mode")
+	jpegQualityPath = data.MustCompilePath("jpeg_quality")
 )
 
 // TypeImageFormat is an ID of image format type.
@@ -25,6 +29,16 @@ const (
 	TypeCVMAT4b
 	// TypeJPEG is JPEG format
 	TypeJPEG
+	// TypePNG is PNG format
+	TypePNG
+	// TypeWEBP is WebP format, which is supported when the linked OpenCV
+	// supports it.
+	TypeWEBP
+	// TypeBMP is BMP format
+	TypeBMP
+	// TypeCVMAT1b is OpenCV cv::Mat_<uchar> format, which has single channel
+	// (e.g. grayscale).
+	TypeCVMAT1b
 )
 
 func (t TypeImageFormat) String() string {
@@ -35,11 +49,72 @@ func (t TypeImageFormat) String() string {
 		return "cvmat4b"
 	case TypeJPEG:
 		return "jpeg"
+	case TypePNG:
+		return "png"
+	case TypeWEBP:
+		return "webp"
+	case TypeBMP:
+		return "bmp"
+	case TypeCVMAT1b:
+		return "cvmat1b"
 	default:
 		return "unknown"
 	}
 }
 
+// channels returns the number of channels of raw image formats. Returns 0
+// for compressed formats.
+func (t TypeImageFormat) channels() int {
+	switch t {
+	case TypeCVMAT:
+		return 3
+	case TypeCVMAT4b:
+		return 4
+	case TypeCVMAT1b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// rawFormatOfChannels returns the raw image format which has the number of
+// channels.
+func rawFormatOfChannels(channels int) TypeImageFormat {
+	switch channels {
+	case 3:
+		return TypeCVMAT
+	case 4:
+		return TypeCVMAT4b
+	case 1:
+		return TypeCVMAT1b
+	default:
+		return typeUnknownFormat
+	}
+}
+
+// IsCompressed returns the format is a compressed image format (e.g. JPEG)
+// or not.
+func (t TypeImageFormat) IsCompressed() bool {
+	return t.extension() != ""
+}
+
+// extension returns the file extension which is used by OpenCV to decide the
+// encoder.
+func (t TypeImageFormat) extension() string {
+	switch t {
+	case TypeJPEG:
+		return ".jpg"
+	case TypePNG:
+		return ".png"
+	case TypeWEBP:
+		return ".webp"
+	case TypeBMP:
+		return ".bmp"
+	default:
+		return ""
+	}
+}
+
 // GetTypeImageFormat returns image format type.
 func GetTypeImageFormat(str string) TypeImageFormat {
 	switch str {
@@ -49,19 +124,142 @@ func GetTypeImageFormat(str string) TypeImageFormat {
 		return TypeCVMAT4b
 	case "jpeg":
 		return TypeJPEG
+	case "png":
+		return TypePNG
+	case "webp":
+		return TypeWEBP
+	case "bmp":
+		return TypeBMP
+	case "cvmat1b":
+		return TypeCVMAT1b
 	default:
 		return typeUnknownFormat
 	}
 }
 
-// RawData is represented of `cv::Mat_<cv::Vec3b>` structure.
+// TypeColorMode is an ID of color mode (i.e. the order and the meaning of
+// channels) of raw image formats.
+type TypeColorMode int
+
+const (
+	// typeDefaultMode means the default mode of the format, which is BGR for
+	// cvmat, BGRA for cvmat4b and GRAY for cvmat1b.
+	typeDefaultMode TypeColorMode = iota
+	// ModeBGR is Blue, Green and Red order, which is OpenCV's default.
+	ModeBGR
+	// ModeRGB is Red, Green and Blue order.
+	ModeRGB
+	// ModeGRAY is single channel grayscale.
+	ModeGRAY
+	// ModeHSV is Hue, Saturation and Value, Hue is in [0, 180).
+	ModeHSV
+	// ModeYCrCb is luma and chroma components.
+	ModeYCrCb
+	// ModeBGRA is BGR with alpha channel.
+	ModeBGRA
+)
+
+func (m TypeColorMode) String() string {
+	switch m {
+	case ModeBGR:
+		return "BGR"
+	case ModeRGB:
+		return "RGB"
+	case ModeGRAY:
+		return "GRAY"
+	case ModeHSV:
+		return "HSV"
+	case ModeYCrCb:
+		return "YCrCb"
+	case ModeBGRA:
+		return "BGRA"
+	default:
+		return "unknown"
+	}
+}
+
+// channels returns the number of channels of the color mode.
+func (m TypeColorMode) channels() int {
+	switch m {
+	case ModeBGR, ModeRGB, ModeHSV, ModeYCrCb:
+		return 3
+	case ModeBGRA:
+		return 4
+	case ModeGRAY:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// GetTypeColorMode returns color mode type. Returns an error when the mode is
+// not supported.
+func GetTypeColorMode(str string) (TypeColorMode, error) {
+	switch str {
+	case "BGR":
+		return ModeBGR, nil
+	case "RGB":
+		return ModeRGB, nil
+	case "GRAY":
+		return ModeGRAY, nil
+	case "HSV":
+		return ModeHSV, nil
+	case "YCrCb":
+		return ModeYCrCb, nil
+	case "BGRA":
+		return ModeBGRA, nil
+	default:
+		return typeDefaultMode, fmt.Errorf("'%v' mode is not supported", str)
+	}
+}
+
+// RawData is represented of `cv::Mat_<cv::Vec3b>` structure. Mode is the
+// color mode of raw image formats, and the zero value means the default mode
+// of the format.
 type RawData struct {
 	Format TypeImageFormat
+	Mode   TypeColorMode
 	Width  int
 	Height int
 	Data   []byte
 }
 
+// ColorMode returns the color mode of the image. Returns the default mode of
+// the format when Mode is not set, and the zero value for compressed formats.
+func (r *RawData) ColorMode() TypeColorMode {
+	if r.Mode != typeDefaultMode {
+		return r.Mode
+	}
+	switch r.Format {
+	case TypeCVMAT:
+		return ModeBGR
+	case TypeCVMAT4b:
+		return ModeBGRA
+	case TypeCVMAT1b:
+		return ModeGRAY
+	default:
+		return typeDefaultMode
+	}
+}
+
+// checkSize returns an error when the size of raw image formats is not
+// positive or the data is shorter than the size.
+func (r *RawData) checkSize() error {
+	channels := r.Format.channels()
+	if channels == 0 {
+		return nil
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		return fmt.Errorf("image size must be positive: %vx%v", r.Width,
+			r.Height)
+	}
+	if len(r.Data) < r.Width*r.Height*channels {
+		return fmt.Errorf("image data is shorter than %vx%v", r.Width,
+			r.Height)
+	}
+	return nil
+}
+
 // ToRawData converts MatVec3b to RawData.
 func ToRawData(m bridge.MatVec3b) RawData {
 	w, h, data := m.ToRawData()
@@ -73,26 +271,360 @@ func ToRawData(m bridge.MatVec3b) RawData {
 	}
 }
 
-// ToMatVec3b converts RawData to MatVec3b. Returned MatVec3b is required to
-// delete after using.
+// ToRawData4b converts MatVec4b to RawData.
+func ToRawData4b(m bridge.MatVec4b) RawData {
+	w, h, data := m.ToRawData()
+	return RawData{
+		Format: TypeCVMAT4b,
+		Width:  w,
+		Height: h,
+		Data:   data,
+	}
+}
+
+// ToRawDataGray converts MatGray to RawData.
+func ToRawDataGray(m bridge.MatGray) RawData {
+	w, h, data := m.ToRawData()
+	return RawData{
+		Format: TypeCVMAT1b,
+		Width:  w,
+		Height: h,
+		Data:   data,
+	}
+}
+
+// ToMatVec3b converts RawData to MatVec3b. Compressed image data (e.g. JPEG)
+// is decoded, the alpha channel of cvmat4b is dropped, and other color modes
+// are converted to BGR. Returned MatVec3b is required to delete after using.
 func (r *RawData) ToMatVec3b() (bridge.MatVec3b, error) {
-	if r.Format != TypeCVMAT {
+	if err := r.checkSize(); err != nil {
+		return bridge.MatVec3b{}, err
+	}
+	if r.Format == TypeCVMAT && r.ColorMode() != ModeBGR {
+		bgr, err := r.ConvertColor(ModeBGR)
+		if err != nil {
+			return bridge.MatVec3b{}, err
+		}
+		return bgr.ToMatVec3b()
+	}
+	switch {
+	case r.Format == TypeCVMAT:
+		return bridge.ToMatVec3b(r.Width, r.Height, r.Data), nil
+	case r.Format == TypeCVMAT1b:
+		m := bridge.ToMatGray(r.Width, r.Height, r.Data)
+		defer m.Delete()
+		return m.ToMatVec3b(), nil
+	case r.Format == TypeCVMAT4b:
+		m := bridge.ToMatVec4b(r.Width, r.Height, r.Data)
+		defer m.Delete()
+		return m.ToMatVec3b(), nil
+	case r.Format.IsCompressed():
+		m := bridge.DecodeToMatVec3b(r.Data)
+		if m.Empty() {
+			m.Delete()
+			return bridge.MatVec3b{}, fmt.Errorf("cannot decode '%v' image data",
+				r.Format)
+		}
+		return m, nil
+	default:
 		return bridge.MatVec3b{}, fmt.Errorf("'%v' cannot convert to 'MatVec3b'",
 			r.Format)
 	}
-	return bridge.ToMatVec3b(r.Width, r.Height, r.Data), nil
+}
+
+// toRawDataWithFormat converts MatVec3b to RawData of the format. This
+// function is used to return a frame in the original format of the argument.
+// Alpha values of cvmat4b are filled with 255, see copyAlpha to keep them.
+func toRawDataWithFormat(m bridge.MatVec3b, format TypeImageFormat) (RawData,
+	error) {
+	switch {
+	case format == TypeCVMAT:
+		return ToRawData(m), nil
+	case format == TypeCVMAT4b:
+		m4 := m.ToMatVec4b()
+		defer m4.Delete()
+		return ToRawData4b(m4), nil
+	case format == TypeCVMAT1b:
+		gray := m.ToMatGray()
+		defer gray.Delete()
+		return ToRawDataGray(gray), nil
+	case format.IsCompressed():
+		b, ok := m.Encode(format.extension(), nil)
+		if !ok {
+			return RawData{}, fmt.Errorf("cannot encode to '%v'", format)
+		}
+		return RawData{
+			Format: format,
+			Width:  m.Cols(),
+			Height: m.Rows(),
+			Data:   b,
+		}, nil
+	default:
+		return RawData{}, fmt.Errorf("cannot convert to '%v'", format)
+	}
+}
+
+// toMat converts RawData to Mat in its own channel layout, i.e. the alpha
+// channel of cvmat4b and the color mode are kept. Compressed image data is
+// decoded to BGR. Returned Mat is required to delete after using.
+func (r *RawData) toMat() (bridge.Mat, error) {
+	if r.Format.IsCompressed() {
+		m, err := r.ToMatVec3b()
+		if err != nil {
+			return bridge.Mat{}, err
+		}
+		defer m.Delete()
+		return m.ToMat(), nil
+	}
+	if err := r.checkSize(); err != nil {
+		return bridge.Mat{}, err
+	}
+	channels := r.Format.channels()
+	if channels == 0 {
+		return bridge.Mat{}, fmt.Errorf("'%v' cannot convert to 'Mat'",
+			r.Format)
+	}
+	return bridge.NewMatFromBytes(r.Height, r.Width,
+		bridge.MatType(bridge.MatDepth8U, channels), r.Data), nil
+}
+
+// fromMat converts Mat which has the layout returned by toMat to RawData in
+// the same format and color mode as r.
+func (r *RawData) fromMat(m bridge.Mat) (RawData, error) {
+	if r.Format.IsCompressed() {
+		mat := m.ToMatVec3b()
+		defer mat.Delete()
+		return toRawDataWithFormat(mat, r.Format)
+	}
+	return RawData{
+		Format: r.Format,
+		Mode:   r.Mode,
+		Width:  m.Cols(),
+		Height: m.Rows(),
+		Data:   m.ToBytes(),
+	}, nil
+}
+
+// copyAlpha copies the alpha channel of the source cvmat4b image of the same
+// size, which restores alpha values of a frame processed as BGR.
+func (r *RawData) copyAlpha(src *RawData) {
+	if r.Format != TypeCVMAT4b || src.Format != TypeCVMAT4b ||
+		r.Width != src.Width || r.Height != src.Height {
+		return
+	}
+	for i := 3; i < r.Width*r.Height*4; i += 4 {
+		r.Data[i] = src.Data[i]
+	}
+}
+
+// ToMatVec4b converts RawData to MatVec4b. Compressed image data is decoded,
+// and when the image does not have alpha channel, the alpha values are filled
+// with 255. Returned MatVec4b is required to delete after using.
+func (r *RawData) ToMatVec4b() (bridge.MatVec4b, error) {
+	if err := r.checkSize(); err != nil {
+		return bridge.MatVec4b{}, err
+	}
+	if r.Format == TypeCVMAT4b {
+		return bridge.ToMatVec4b(r.Width, r.Height, r.Data), nil
+	}
+	if !r.Format.IsCompressed() {
+		return bridge.MatVec4b{}, fmt.Errorf("'%v' cannot convert to 'MatVec4b'",
+			r.Format)
+	}
+	m := bridge.DecodeToMatVec4b(r.Data)
+	if m.Empty() {
+		m.Delete()
+		return bridge.MatVec4b{}, fmt.Errorf("cannot decode '%v' image data",
+			r.Format)
+	}
+	return m, nil
+}
+
+// ToMatGray converts RawData to MatGray. Compressed image data is decoded as
+// grayscale, and other color modes are converted to GRAY. Returned MatGray is
+// required to delete after using.
+func (r *RawData) ToMatGray() (bridge.MatGray, error) {
+	switch {
+	case r.Format == TypeCVMAT1b:
+		if err := r.checkSize(); err != nil {
+			return bridge.MatGray{}, err
+		}
+		return bridge.ToMatGray(r.Width, r.Height, r.Data), nil
+	case r.Format.IsCompressed():
+		m := bridge.DecodeToMatGray(r.Data)
+		if m.Empty() {
+			m.Delete()
+			return bridge.MatGray{}, fmt.Errorf("cannot decode '%v' image data",
+				r.Format)
+		}
+		return m, nil
+	case r.Format.channels() > 0:
+		gray, err := r.ConvertColor(ModeGRAY)
+		if err != nil {
+			return bridge.MatGray{}, err
+		}
+		return gray.ToMatGray()
+	default:
+		return bridge.MatGray{}, fmt.Errorf("'%v' cannot convert to 'MatGray'",
+			r.Format)
+	}
+}
+
+// Encode encodes RawData to the compressed format (e.g. TypePNG). params are
+// pairs of OpenCV parameter ID and its value, see bridge.CvImwriteJpegQuality
+// and so on. When RawData is already compressed, the data is decoded once.
+func (r *RawData) Encode(format TypeImageFormat, params ...int) (RawData, error) {
+	if !format.IsCompressed() {
+		return RawData{}, fmt.Errorf("'%v' is not an encoding format", format)
+	}
+	if err := r.checkSize(); err != nil {
+		return RawData{}, err
+	}
+
+	if r.Format == TypeCVMAT && r.ColorMode() != ModeBGR {
+		bgr, err := r.ConvertColor(ModeBGR)
+		if err != nil {
+			return RawData{}, err
+		}
+		return bgr.Encode(format, params...)
+	}
+
+	var (
+		b  []byte
+		ok bool
+	)
+	switch {
+	case r.Format == TypeCVMAT:
+		mat := bridge.ToMatVec3b(r.Width, r.Height, r.Data)
+		defer mat.Delete()
+		b, ok = mat.Encode(format.extension(), params)
+	case r.Format == TypeCVMAT4b:
+		mat := bridge.ToMatVec4b(r.Width, r.Height, r.Data)
+		defer mat.Delete()
+		b, ok = mat.Encode(format.extension(), params)
+	case r.Format == TypeCVMAT1b:
+		mat, err := r.ToMatGray()
+		if err != nil {
+			return RawData{}, err
+		}
+		defer mat.Delete()
+		b, ok = mat.Encode(format.extension(), params)
+	case r.Format.IsCompressed():
+		decoded, err := r.Decode(TypeCVMAT)
+		if err != nil {
+			return RawData{}, err
+		}
+		return decoded.Encode(format, params...)
+	default:
+		return RawData{}, fmt.Errorf("'%v' cannot be encoded", r.Format)
+	}
+	if !ok {
+		return RawData{}, fmt.Errorf("cannot encode to '%v'", format)
+	}
+	return RawData{
+		Format: format,
+		Width:  r.Width,
+		Height: r.Height,
+		Data:   b,
+	}, nil
+}
+
+// Decode decodes compressed RawData to the format, which is TypeCVMAT,
+// TypeCVMAT4b or TypeCVMAT1b. When RawData is already the format, returns itself.
+func (r *RawData) Decode(format TypeImageFormat) (RawData, error) {
+	if r.Format == format {
+		return *r, nil
+	}
+	if !r.Format.IsCompressed() {
+		return RawData{}, fmt.Errorf("'%v' cannot be decoded", r.Format)
+	}
+	return decodeImageData(r.Data, format)
+}
+
+// decodeImageData decodes compressed image data. The image format of the data
+// is detected by OpenCV.
+func decodeImageData(b []byte, format TypeImageFormat) (RawData, error) {
+	switch format {
+	case TypeCVMAT:
+		mat := bridge.DecodeToMatVec3b(b)
+		defer mat.Delete()
+		if mat.Empty() {
+			return RawData{}, fmt.Errorf("cannot decode the image data")
+		}
+		return ToRawData(mat), nil
+	case TypeCVMAT4b:
+		mat := bridge.DecodeToMatVec4b(b)
+		defer mat.Delete()
+		if mat.Empty() {
+			return RawData{}, fmt.Errorf("cannot decode the image data")
+		}
+		return ToRawData4b(mat), nil
+	case TypeCVMAT1b:
+		mat := bridge.DecodeToMatGray(b)
+		defer mat.Delete()
+		if mat.Empty() {
+			return RawData{}, fmt.Errorf("cannot decode the image data")
+		}
+		return ToRawDataGray(mat), nil
+	default:
+		return RawData{}, fmt.Errorf("cannot decode to '%v'", format)
+	}
 }
 
 func toRawMap(m *bridge.MatVec3b) data.Map {
 	r := ToRawData(*m)
 	return data.Map{
 		"format": data.String(r.Format.String()), // = cv::Mat_<cv::Vec3b> = "cvmat"
+		"mode":   data.String(ModeBGR.String()),
 		"width":  data.Int(r.Width),
 		"height": data.Int(r.Height),
 		"image":  data.Blob(r.Data),
 	}
 }
 
+func toJpegMapFunc(quality int) func(m *bridge.MatVec3b) data.Map {
+	return func(m *bridge.MatVec3b) data.Map {
+		return data.Map{
+			"format": data.String(TypeJPEG.String()),
+			"width":  data.Int(m.Cols()),
+			"height": data.Int(m.Rows()),
+			"image":  data.Blob(m.ToJpegData(quality)),
+		}
+	}
+}
+
+// newFormatFunc returns a function which converts a captured frame to a
+// data.Map. The output format is decided by "format" parameter, and default
+// is "cvmat". When the format is "jpeg", "jpeg_quality" parameter is also
+// used, default quality is 95.
+func newFormatFunc(params data.Map) (func(m *bridge.MatVec3b) data.Map, error) {
+	format := "cvmat"
+	if fm, err := params.Get(formatPath); err == nil {
+		if format, err = data.AsString(fm); err != nil {
+			return nil, err
+		}
+	}
+
+	switch GetTypeImageFormat(format) {
+	case TypeCVMAT:
+		return toRawMap, nil
+	case TypeJPEG:
+		quality := int64(95)
+		if q, err := params.Get(jpegQualityPath); err == nil {
+			if quality, err = data.AsInt(q); err != nil {
+				return nil, err
+			}
+			if quality < 0 || quality > 100 {
+				return nil, fmt.Errorf("jpeg_quality must be in [0, 100]: %v",
+					quality)
+			}
+		}
+		return toJpegMapFunc(int(quality)), nil
+	default:
+		return nil, fmt.Errorf("'%v' format is not supported", format)
+	}
+}
+
 // ConvertMapToRawData returns RawData from data.Map. This function is
 // utility method for other plug-in.
 func ConvertMapToRawData(dm data.Map) (RawData, error) {
@@ -129,8 +661,24 @@ func ConvertMapToRawData(dm data.Map) (RawData, error) {
 		}
 	}
 
+	mode := typeDefaultMode
+	if m, err := dm.Get(modePath); err == nil {
+		modeStr, err := data.AsString(m)
+		if err != nil {
+			return RawData{}, err
+		}
+		if mode, err = GetTypeColorMode(modeStr); err != nil {
+			return RawData{}, err
+		}
+		if mode.channels() != format.channels() {
+			return RawData{}, fmt.Errorf("'%v' mode cannot be used with '%v'",
+				mode, format)
+		}
+	}
+
 	return RawData{
 		Format: format,
+		Mode:   mode,
 		Width:  int(width),
 		Height: int(height),
 		Data:   img,
@@ -138,51 +686,188 @@ func ConvertMapToRawData(dm data.Map) (RawData, error) {
 }
 
 // ConvertToDataMap returns data.map. This function is utility method for
-// other plug-in.
+// other plug-in. "mode" is set only for raw image formats.
 func (r *RawData) ConvertToDataMap() data.Map {
-	return data.Map{
+	m := data.Map{
 		"format": data.String(r.Format.String()),
 		"width":  data.Int(r.Width),
 		"height": data.Int(r.Height),
 		"image":  data.Blob(r.Data),
 	}
+	if mode := r.ColorMode(); mode != typeDefaultMode {
+		m["mode"] = data.String(mode.String())
+	}
+	return m
 }
 
-// ToImage converts to image.Image
+// ToImage converts to image.Image. Raw image formats are converted to
+// *image.RGBA (cvmat), *image.NRGBA (cvmat4b) or *image.Gray (cvmat1b)
+// respecting the color mode, and HSV or YCrCb images are converted to BGR
+// beforehand. JPEG and PNG data are decoded by the standard image package,
+// and other compressed formats are decoded by OpenCV.
 func (r *RawData) ToImage() (image.Image, error) {
-	// BGR to RGB
-	rgba := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
-	if r.Format == TypeCVMAT {
-		for i, j := 0, 0; i < len(rgba.Pix); i, j = i+4, j+3 {
-			rgba.Pix[i+0] = r.Data[j+2]
-			rgba.Pix[i+1] = r.Data[j+1]
-			rgba.Pix[i+2] = r.Data[j+0]
-			rgba.Pix[i+3] = 0xFF
-		}
-	} else if r.Format == TypeCVMAT4b {
-		for i, j := 0, 0; i < len(rgba.Pix); i, j = i+4, j+3 {
-			rgba.Pix[i+0] = r.Data[j+2]
-			rgba.Pix[i+1] = r.Data[j+1]
-			rgba.Pix[i+2] = r.Data[j+0]
-			rgba.Pix[i+3] = r.Data[j+3]
+	switch r.Format {
+	case TypeJPEG:
+		return jpeg.Decode(bytes.NewReader(r.Data))
+	case TypePNG:
+		return png.Decode(bytes.NewReader(r.Data))
+	case TypeWEBP, TypeBMP:
+		decoded, err := r.Decode(TypeCVMAT)
+		if err != nil {
+			return nil, err
 		}
-	} else {
+		return decoded.ToImage()
+	}
+	if r.Format.channels() == 0 {
 		return nil, fmt.Errorf("'%v' cannot convert to image", r.Format)
 	}
-	return rgba, nil
+	mode := r.ColorMode()
+	if mode.channels() != r.Format.channels() {
+		return nil, fmt.Errorf("'%v' mode does not match '%v'", mode, r.Format)
+	}
+	if err := r.checkSize(); err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case ModeBGR:
+		return toRGBAImage(r.Data, r.Width, r.Height, false), nil
+	case ModeRGB:
+		return toRGBAImage(r.Data, r.Width, r.Height, true), nil
+	case ModeBGRA:
+		return toNRGBAImage(r.Data, r.Width, r.Height), nil
+	case ModeGRAY:
+		img := image.NewGray(image.Rect(0, 0, r.Width, r.Height))
+		copy(img.Pix, r.Data)
+		return img, nil
+	default:
+		bgr, err := r.ConvertColor(ModeBGR)
+		if err != nil {
+			return nil, err
+		}
+		return bgr.ToImage()
+	}
+}
+
+// toRGBAImage converts 3 channels image data to *image.RGBA. The data is in
+// BGR order unless rgb is true.
+func toRGBAImage(b []byte, width, height int, rgb bool) *image.RGBA {
+	ri, bi := 2, 0
+	if rgb {
+		ri, bi = 0, 2
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i, j := 0, 0; i < len(img.Pix); i, j = i+4, j+3 {
+		img.Pix[i+0] = b[j+ri]
+		img.Pix[i+1] = b[j+1]
+		img.Pix[i+2] = b[j+bi]
+		img.Pix[i+3] = 0xFF
+	}
+	return img
+}
+
+// toNRGBAImage converts BGRA image data to *image.NRGBA. The alpha channel of
+// OpenCV is not premultiplied.
+func toNRGBAImage(b []byte, width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i+0] = b[i+2]
+		img.Pix[i+1] = b[i+1]
+		img.Pix[i+2] = b[i+0]
+		img.Pix[i+3] = b[i+3]
+	}
+	return img
+}
+
+// FromImage converts image.Image to RawData. *image.Gray is converted to
+// cvmat1b, *image.NRGBA is converted to cvmat4b keeping the alpha channel, and
+// other images (e.g. *image.RGBA and *image.YCbCr) are converted to cvmat in
+// BGR order. The alpha channel of other images is dropped, which means that
+// premultiplied colors are composed on black.
+func FromImage(img image.Image) RawData {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	switch src := img.(type) {
+	case *image.Gray:
+		b := make([]byte, w*h)
+		for y := 0; y < h; y++ {
+			i := src.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(b[y*w:(y+1)*w], src.Pix[i:i+w])
+		}
+		return RawData{
+			Format: TypeCVMAT1b,
+			Width:  w,
+			Height: h,
+			Data:   b,
+		}
+	case *image.NRGBA:
+		b := make([]byte, w*h*4)
+		for y := 0; y < h; y++ {
+			i := src.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			for x, j := 0, y*w*4; x < w; x, i, j = x+1, i+4, j+4 {
+				b[j+0] = src.Pix[i+2]
+				b[j+1] = src.Pix[i+1]
+				b[j+2] = src.Pix[i+0]
+				b[j+3] = src.Pix[i+3]
+			}
+		}
+		return RawData{
+			Format: TypeCVMAT4b,
+			Width:  w,
+			Height: h,
+			Data:   b,
+		}
+	}
+
+	b := make([]byte, w*h*3)
+	switch src := img.(type) {
+	case *image.RGBA:
+		for y := 0; y < h; y++ {
+			i := src.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			for x, j := 0, y*w*3; x < w; x, i, j = x+1, i+4, j+3 {
+				b[j+0] = src.Pix[i+2]
+				b[j+1] = src.Pix[i+1]
+				b[j+2] = src.Pix[i+0]
+			}
+		}
+	case *image.YCbCr:
+		for y := 0; y < h; y++ {
+			for x, j := 0, y*w*3; x < w; x, j = x+1, j+3 {
+				yi := src.YOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				ci := src.COffset(bounds.Min.X+x, bounds.Min.Y+y)
+				r, g, bl := color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+				b[j+0], b[j+1], b[j+2] = bl, g, r
+			}
+		}
+	default:
+		for y := 0; y < h; y++ {
+			for x, j := 0, y*w*3; x < w; x, j = x+1, j+3 {
+				r, g, bl, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				b[j+0], b[j+1], b[j+2] = byte(bl>>8), byte(g>>8), byte(r>>8)
+			}
+		}
+	}
+	return RawData{
+		Format: TypeCVMAT,
+		Width:  w,
+		Height: h,
+		Data:   b,
+	}
 }
 
-// ToJpegData convert JPGE format image bytes.
+// ToJpegData converts to JPEG format image bytes with the standard image
+// package. JPEG data is returned as it is, and other formats are converted
+// via ToImage.
 func (r *RawData) ToJpegData(quality int) ([]byte, error) {
 	if r.Format == TypeJPEG {
 		return r.Data, nil
 	}
-	rgba, err := r.ToImage()
+	img, err := r.ToImage()
 	if err != nil {
 		return []byte{}, err
 	}
 
 	w := bytes.NewBuffer([]byte{})
-	err = jpeg.Encode(w, rgba, &jpeg.Options{Quality: quality})
+	err = jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
 	return w.Bytes(), err
 }
@@ -0,0 +1,256 @@
+package opencv
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestRawDataToImage(t *testing.T) {
+	Convey("Given a 2x1 BGR cvmat image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Width:  2,
+			Height: 1,
+			Data:   []byte{1, 2, 3, 4, 5, 6},
+		}
+		Convey("When convert it to image.Image", func() {
+			img, err := raw.ToImage()
+			So(err, ShouldBeNil)
+			Convey("Then pixels should be RGB order", func() {
+				So(img.Bounds(), ShouldResemble, image.Rect(0, 0, 2, 1))
+				So(img.At(0, 0), ShouldResemble, color.RGBA{3, 2, 1, 255})
+				So(img.At(1, 0), ShouldResemble, color.RGBA{6, 5, 4, 255})
+			})
+		})
+		Convey("When the mode is RGB", func() {
+			raw.Mode = ModeRGB
+			img, err := raw.ToImage()
+			So(err, ShouldBeNil)
+			Convey("Then channels should not be swapped", func() {
+				So(img.At(0, 0), ShouldResemble, color.RGBA{1, 2, 3, 255})
+			})
+		})
+		Convey("When the data is shorter than the size", func() {
+			raw.Data = raw.Data[:5]
+			_, err := raw.ToImage()
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When convert it to JPEG data", func() {
+			b, err := raw.ToJpegData(100)
+			So(err, ShouldBeNil)
+			Convey("Then the data should be decoded as the same size", func() {
+				jpg := RawData{Format: TypeJPEG, Data: b}
+				img, err := jpg.ToImage()
+				So(err, ShouldBeNil)
+				So(img.Bounds(), ShouldResemble, image.Rect(0, 0, 2, 1))
+			})
+		})
+	})
+
+	Convey("Given a 3x1 BGRA cvmat4b image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT4b,
+			Width:  3,
+			Height: 1,
+			Data: []byte{
+				1, 2, 3, 255,
+				4, 5, 6, 128,
+				7, 8, 9, 0,
+			},
+		}
+		Convey("When convert it to image.Image", func() {
+			img, err := raw.ToImage()
+			So(err, ShouldBeNil)
+			Convey("Then each pixel should be read by 4 bytes", func() {
+				So(img.At(0, 0), ShouldResemble, color.NRGBA{3, 2, 1, 255})
+				So(img.At(1, 0), ShouldResemble, color.NRGBA{6, 5, 4, 128})
+				So(img.At(2, 0), ShouldResemble, color.NRGBA{9, 8, 7, 0})
+			})
+		})
+		Convey("When the data is shorter than the size", func() {
+			raw.Data = raw.Data[:9]
+			_, err := raw.ToImage()
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given a 2x2 cvmat1b image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT1b,
+			Width:  2,
+			Height: 2,
+			Data:   []byte{0, 64, 128, 255},
+		}
+		Convey("When convert it to image.Image", func() {
+			img, err := raw.ToImage()
+			So(err, ShouldBeNil)
+			Convey("Then it should be a grayscale image", func() {
+				gray, ok := img.(*image.Gray)
+				So(ok, ShouldBeTrue)
+				So(gray.Pix, ShouldResemble, raw.Data)
+				So(img.At(1, 1), ShouldResemble, color.Gray{255})
+			})
+		})
+		Convey("When the mode does not match the format", func() {
+			raw.Mode = ModeBGR
+			_, err := raw.ToImage()
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+
+	Convey("Given PNG data of an image.Image", t, func() {
+		src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		src.SetNRGBA(0, 0, color.NRGBA{10, 20, 30, 255})
+		src.SetNRGBA(1, 0, color.NRGBA{40, 50, 60, 100})
+		w := bytes.NewBuffer([]byte{})
+		So(png.Encode(w, src), ShouldBeNil)
+		raw := RawData{
+			Format: TypePNG,
+			Width:  2,
+			Height: 1,
+			Data:   w.Bytes(),
+		}
+		Convey("When convert it to image.Image", func() {
+			img, err := raw.ToImage()
+			So(err, ShouldBeNil)
+			Convey("Then it should have the same pixels as the original", func() {
+				So(img.Bounds(), ShouldResemble, src.Bounds())
+				So(color.NRGBAModel.Convert(img.At(0, 0)), ShouldResemble,
+					src.At(0, 0))
+				So(color.NRGBAModel.Convert(img.At(1, 0)), ShouldResemble,
+					src.At(1, 0))
+			})
+		})
+		Convey("When decode it to cvmat4b and convert it to image.Image", func() {
+			decoded, err := raw.Decode(TypeCVMAT4b)
+			So(err, ShouldBeNil)
+			img, err := decoded.ToImage()
+			So(err, ShouldBeNil)
+			Convey("Then it should have the same pixels as the original", func() {
+				So(img, ShouldResemble, src)
+			})
+		})
+	})
+}
+
+func TestFromImage(t *testing.T) {
+	Convey("Given an RGBA image", t, func() {
+		img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		img.SetRGBA(0, 0, color.RGBA{1, 2, 3, 255})
+		img.SetRGBA(1, 0, color.RGBA{4, 5, 6, 255})
+		Convey("When convert it to RawData", func() {
+			raw := FromImage(img)
+			Convey("Then it should be cvmat in BGR order", func() {
+				So(raw.Format, ShouldEqual, TypeCVMAT)
+				So(raw.Width, ShouldEqual, 2)
+				So(raw.Height, ShouldEqual, 1)
+				So(raw.Data, ShouldResemble, []byte{3, 2, 1, 6, 5, 4})
+			})
+			Convey("And convert it back to image.Image", func() {
+				back, err := raw.ToImage()
+				So(err, ShouldBeNil)
+				Convey("Then it should be the same as the original", func() {
+					So(back, ShouldResemble, img)
+				})
+			})
+		})
+		Convey("When convert its sub image to RawData", func() {
+			raw := FromImage(img.SubImage(image.Rect(1, 0, 2, 1)))
+			Convey("Then it should have only pixels in the bounds", func() {
+				So(raw.Width, ShouldEqual, 1)
+				So(raw.Data, ShouldResemble, []byte{6, 5, 4})
+			})
+		})
+	})
+
+	Convey("Given an NRGBA image", t, func() {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		img.SetNRGBA(0, 0, color.NRGBA{1, 2, 3, 255})
+		img.SetNRGBA(1, 0, color.NRGBA{4, 5, 6, 0})
+		Convey("When convert it to RawData", func() {
+			raw := FromImage(img)
+			Convey("Then it should be cvmat4b in BGRA order", func() {
+				So(raw.Format, ShouldEqual, TypeCVMAT4b)
+				So(raw.Data, ShouldResemble, []byte{3, 2, 1, 255, 6, 5, 4, 0})
+				back, err := raw.ToImage()
+				So(err, ShouldBeNil)
+				So(back, ShouldResemble, img)
+			})
+		})
+	})
+
+	Convey("Given a Gray image", t, func() {
+		img := image.NewGray(image.Rect(0, 0, 2, 2))
+		copy(img.Pix, []byte{0, 64, 128, 255})
+		Convey("When convert it to RawData", func() {
+			raw := FromImage(img)
+			Convey("Then it should be cvmat1b", func() {
+				So(raw.Format, ShouldEqual, TypeCVMAT1b)
+				So(raw.Data, ShouldResemble, []byte{0, 64, 128, 255})
+				back, err := raw.ToImage()
+				So(err, ShouldBeNil)
+				So(back, ShouldResemble, img)
+			})
+		})
+	})
+
+	Convey("Given a YCbCr image", t, func() {
+		img := image.NewYCbCr(image.Rect(0, 0, 2, 1), image.YCbCrSubsampleRatio444)
+		img.Y[0], img.Cb[0], img.Cr[0] = 76, 85, 255  // red
+		img.Y[1], img.Cb[1], img.Cr[1] = 29, 255, 107 // blue
+		Convey("When convert it to RawData", func() {
+			raw := FromImage(img)
+			Convey("Then it should be cvmat in BGR order", func() {
+				So(raw.Format, ShouldEqual, TypeCVMAT)
+				So(len(raw.Data), ShouldEqual, 6)
+				r, g, b := color.YCbCrToRGB(76, 85, 255)
+				So(raw.Data[:3], ShouldResemble, []byte{b, g, r})
+				r, g, b = color.YCbCrToRGB(29, 255, 107)
+				So(raw.Data[3:], ShouldResemble, []byte{b, g, r})
+			})
+		})
+	})
+}
+
+func TestRawDataSize(t *testing.T) {
+	Convey("Given raw images of which size is not positive", t, func() {
+		for _, f := range []TypeImageFormat{TypeCVMAT, TypeCVMAT4b, TypeCVMAT1b} {
+			f := f
+			raw := RawData{
+				Format: f,
+				Width:  0,
+				Height: 0,
+				Data:   []byte{},
+			}
+			Convey("When convert "+f.String()+" to MatVec3b", func() {
+				_, err := raw.ToMatVec3b()
+				Convey("Then an error should be occurred", func() {
+					So(err, ShouldNotBeNil)
+				})
+			})
+			Convey("When draw rects on "+f.String(), func() {
+				rects := data.Array{data.Map{
+					"x":      data.Int(0),
+					"y":      data.Int(0),
+					"width":  data.Int(1),
+					"height": data.Int(1),
+				}}
+				_, err := DrawRectsToImage(raw.ConvertToDataMap(), rects)
+				Convey("Then an error should be occurred", func() {
+					So(err, ShouldNotBeNil)
+				})
+			})
+		}
+	})
+}
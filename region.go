@@ -0,0 +1,126 @@
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+)
+
+var (
+	roiPath      = data.MustCompilePath("roi")
+	polygonsPath = data.MustCompilePath("polygons")
+)
+
+type point struct {
+	x float64
+	y float64
+}
+
+// polygon is a list of vertices, the last vertex is connected to the first
+// vertex.
+type polygon []point
+
+// convertToPolygons converts an array of polygons to []polygon. Each polygon
+// is required to be an array of at least 3 points, and each point is required
+// to be a map which has "x" and "y".
+func convertToPolygons(v data.Value) ([]polygon, error) {
+	arr, err := data.AsArray(v)
+	if err != nil {
+		return nil, err
+	}
+	polygons := make([]polygon, len(arr))
+	for i, pv := range arr {
+		points, err := data.AsArray(pv)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) < 3 {
+			return nil, fmt.Errorf("polygon must have at least 3 points: %v",
+				len(points))
+		}
+		poly := make(polygon, len(points))
+		for j, ptv := range points {
+			pt, err := data.AsMap(ptv)
+			if err != nil {
+				return nil, err
+			}
+			var x float64
+			if xv, err := pt.Get(xPath); err != nil {
+				return nil, err
+			} else if x, err = data.ToFloat(xv); err != nil {
+				return nil, err
+			}
+			var y float64
+			if yv, err := pt.Get(yPath); err != nil {
+				return nil, err
+			} else if y, err = data.ToFloat(yv); err != nil {
+				return nil, err
+			}
+			poly[j] = point{x: x, y: y}
+		}
+		polygons[i] = poly
+	}
+	return polygons, nil
+}
+
+// boundingRect returns the minimum rectangle which contains all polygons.
+func boundingRect(polygons []polygon) bridge.Rect {
+	if len(polygons) == 0 {
+		return bridge.Rect{}
+	}
+	minX, minY := polygons[0][0].x, polygons[0][0].y
+	maxX, maxY := minX, minY
+	for _, poly := range polygons {
+		for _, pt := range poly {
+			if pt.x < minX {
+				minX = pt.x
+			}
+			if pt.y < minY {
+				minY = pt.y
+			}
+			if pt.x > maxX {
+				maxX = pt.x
+			}
+			if pt.y > maxY {
+				maxY = pt.y
+			}
+		}
+	}
+	return bridge.Rect{
+		X:      int(minX),
+		Y:      int(minY),
+		Width:  int(maxX) - int(minX) + 1,
+		Height: int(maxY) - int(minY) + 1,
+	}
+}
+
+// contains returns the point is inside of the polygon or not.
+func (poly polygon) contains(pt point) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		pi, pj := poly[i], poly[j]
+		if (pi.y > pt.y) != (pj.y > pt.y) &&
+			pt.x < (pj.x-pi.x)*(pt.y-pi.y)/(pj.y-pi.y)+pi.x {
+			in = !in
+		}
+	}
+	return in
+}
+
+// rectInPolygons returns the center of the rectangle is inside of any
+// polygons or not. When polygons are empty, always returns true.
+func rectInPolygons(polygons []polygon, r bridge.Rect) bool {
+	if len(polygons) == 0 {
+		return true
+	}
+	center := point{
+		x: float64(r.X) + float64(r.Width)/2,
+		y: float64(r.Y) + float64(r.Height)/2,
+	}
+	for _, poly := range polygons {
+		if poly.contains(center) {
+			return true
+		}
+	}
+	return false
+}
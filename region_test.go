@@ -0,0 +1,94 @@
+package opencv
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"testing"
+)
+
+func TestConvertToPolygons(t *testing.T) {
+	Convey("Given an array of polygons", t, func() {
+		v := data.Array{
+			data.Array{
+				data.Map{"x": data.Int(10), "y": data.Int(10)},
+				data.Map{"x": data.Int(50), "y": data.Int(10)},
+				data.Map{"x": data.Float(30.5), "y": data.Int(40)},
+			},
+		}
+		Convey("When convert it to polygons", func() {
+			polygons, err := convertToPolygons(v)
+			Convey("Then polygons should have all points", func() {
+				So(err, ShouldBeNil)
+				So(len(polygons), ShouldEqual, 1)
+				So(polygons[0], ShouldResemble, polygon{
+					{x: 10, y: 10}, {x: 50, y: 10}, {x: 30.5, y: 40},
+				})
+			})
+		})
+	})
+
+	Convey("Given invalid polygons", t, func() {
+		testCases := map[string]data.Value{
+			"not array": data.Int(1),
+			"too few points": data.Array{
+				data.Array{
+					data.Map{"x": data.Int(10), "y": data.Int(10)},
+					data.Map{"x": data.Int(50), "y": data.Int(10)},
+				},
+			},
+			"point without y": data.Array{
+				data.Array{
+					data.Map{"x": data.Int(10), "y": data.Int(10)},
+					data.Map{"x": data.Int(50), "y": data.Int(10)},
+					data.Map{"x": data.Int(30)},
+				},
+			},
+		}
+		for name, v := range testCases {
+			v := v
+			Convey("When convert "+name, func() {
+				_, err := convertToPolygons(v)
+				Convey("Then an error should be occurred", func() {
+					So(err, ShouldNotBeNil)
+				})
+			})
+		}
+	})
+}
+
+func TestPolygonRegion(t *testing.T) {
+	Convey("Given polygons", t, func() {
+		polygons := []polygon{
+			{{x: 10, y: 10}, {x: 50, y: 10}, {x: 50, y: 50}, {x: 10, y: 50}},
+			{{x: 100, y: 100}, {x: 200, y: 100}, {x: 150, y: 180}},
+		}
+		Convey("When get the bounding rectangle", func() {
+			r := boundingRect(polygons)
+			Convey("Then the rectangle should contain all polygons", func() {
+				So(r, ShouldResemble, bridge.Rect{X: 10, Y: 10, Width: 191,
+					Height: 171})
+			})
+		})
+		Convey("When check rectangles", func() {
+			Convey("Then a rectangle whose center is inside should be accepted", func() {
+				So(rectInPolygons(polygons, bridge.Rect{X: 20, Y: 20, Width: 10,
+					Height: 10}), ShouldBeTrue)
+				So(rectInPolygons(polygons, bridge.Rect{X: 140, Y: 110, Width: 20,
+					Height: 20}), ShouldBeTrue)
+			})
+			Convey("Then a rectangle whose center is outside should be rejected", func() {
+				So(rectInPolygons(polygons, bridge.Rect{X: 60, Y: 60, Width: 10,
+					Height: 10}), ShouldBeFalse)
+				So(rectInPolygons(polygons, bridge.Rect{X: 100, Y: 160, Width: 10,
+					Height: 10}), ShouldBeFalse)
+			})
+		})
+		Convey("When check a rectangle without polygons", func() {
+			Convey("Then the rectangle should be always accepted", func() {
+				So(rectInPolygons(nil, bridge.Rect{X: 60, Y: 60, Width: 10,
+					Height: 10}), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,345 @@
+package opencv
+
+import (
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"math"
+)
+
+var (
+	resizeWidthPath  = data.MustCompilePath("resize_width")
+	resizeHeightPath = data.MustCompilePath("resize_height")
+)
+
+// toInterpolation converts the name of an interpolation method to OpenCV's
+// flag. When the name is not given, returns bilinear interpolation.
+func toInterpolation(interpolation []string) (int, error) {
+	if len(interpolation) > 1 {
+		return 0, fmt.Errorf("interpolation must be only one string")
+	}
+	if len(interpolation) == 0 {
+		return bridge.InterLinear, nil
+	}
+	switch interpolation[0] {
+	case "nearest":
+		return bridge.InterNearest, nil
+	case "linear":
+		return bridge.InterLinear, nil
+	case "cubic":
+		return bridge.InterCubic, nil
+	case "area":
+		return bridge.InterArea, nil
+	case "lanczos4":
+		return bridge.InterLanczos4, nil
+	default:
+		return 0, fmt.Errorf("'%v' interpolation is not supported",
+			interpolation[0])
+	}
+}
+
+// resizeRawData resizes the image with `cv::resize` and returns it in the
+// same format and color mode as the original.
+func resizeRawData(raw RawData, width, height, interpolation int) (RawData,
+	error) {
+	if width <= 0 || height <= 0 {
+		return RawData{}, fmt.Errorf("size must be greater than 0: %vx%v",
+			width, height)
+	}
+	mat, err := raw.toMat()
+	if err != nil {
+		return RawData{}, err
+	}
+	defer mat.Delete()
+	resized := mat.Resize(width, height, interpolation)
+	defer resized.Delete()
+	if resized.Empty() {
+		return RawData{}, fmt.Errorf("cannot resize the image to %vx%v",
+			width, height)
+	}
+	return raw.fromMat(resized)
+}
+
+// borderValue returns the value of each channel which means black in the
+// color mode, opaque for BGRA.
+func borderValue(mode TypeColorMode) [4]float64 {
+	switch mode {
+	case ModeYCrCb:
+		return [4]float64{0, 128, 128, 0}
+	case ModeBGRA:
+		return [4]float64{0, 0, 0, 255}
+	default:
+		return [4]float64{}
+	}
+}
+
+// Resize returns the image resized to the size in the same format as the
+// original. When width or height is 0, the value is decided by the other
+// with keeping the aspect ratio. The color mode and the alpha channel of
+// cvmat4b are kept.
+//
+// img: target image as RawData map structure.
+//
+// width: width of the resized image.
+//
+// height: height of the resized image.
+//
+// interpolation: [optional] "nearest", "linear", "cubic", "area" or
+// "lanczos4", default is "linear". "area" is preferred for shrinking.
+func Resize(img data.Map, width, height int, interpolation ...string) (
+	data.Map, error) {
+	inter, err := toInterpolation(interpolation)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	if width == 0 && height == 0 {
+		return nil, fmt.Errorf("width or height must be set")
+	}
+	width, height = keepAspectSize(raw.Width, raw.Height, width, height)
+	resized, err := resizeRawData(raw, width, height, inter)
+	if err != nil {
+		return nil, err
+	}
+	return resized.ConvertToDataMap(), nil
+}
+
+// Scale returns the image scaled by the factor in the same format as the
+// original, e.g. factor 0.5 halves width and height. The color mode and the
+// alpha channel of cvmat4b are kept as Resize.
+//
+// img: target image as RawData map structure.
+//
+// factor: scale factor, required to be greater than 0.
+//
+// interpolation: [optional] see Resize.
+func Scale(img data.Map, factor float64, interpolation ...string) (data.Map,
+	error) {
+	if factor <= 0 {
+		return nil, fmt.Errorf("factor must be greater than 0: %v", factor)
+	}
+	inter, err := toInterpolation(interpolation)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	width, height := scaleSize(raw.Width, raw.Height, factor)
+	resized, err := resizeRawData(raw, width, height, inter)
+	if err != nil {
+		return nil, err
+	}
+	return resized.ConvertToDataMap(), nil
+}
+
+// keepAspectSize returns the size of which width or height is 0 decided by
+// the other with keeping the aspect ratio of the source size. The decided
+// length is at least 1 even when the aspect ratio is extreme.
+func keepAspectSize(srcWidth, srcHeight, width, height int) (int, int) {
+	switch {
+	case width == 0 && height > 0 && srcHeight > 0:
+		width = int(math.Floor(float64(srcWidth)*float64(height)/
+			float64(srcHeight) + 0.5))
+		if width < 1 {
+			width = 1
+		}
+	case height == 0 && width > 0 && srcWidth > 0:
+		height = int(math.Floor(float64(srcHeight)*float64(width)/
+			float64(srcWidth) + 0.5))
+		if height < 1 {
+			height = 1
+		}
+	}
+	return width, height
+}
+
+// scaleSize returns the size scaled by the factor, each length is at least 1.
+func scaleSize(width, height int, factor float64) (int, int) {
+	w := int(math.Floor(float64(width)*factor + 0.5))
+	h := int(math.Floor(float64(height)*factor + 0.5))
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+type letterboxGeometry struct {
+	scale   float64
+	width   int
+	height  int
+	offsetX int
+	offsetY int
+}
+
+// newLetterboxGeometry returns the size of the resized image and offsets of
+// it to fit the image into the size with keeping the aspect ratio.
+func newLetterboxGeometry(srcWidth, srcHeight, dstWidth,
+	dstHeight int) letterboxGeometry {
+	scale := math.Min(float64(dstWidth)/float64(srcWidth),
+		float64(dstHeight)/float64(srcHeight))
+	w, h := scaleSize(srcWidth, srcHeight, scale)
+	if w > dstWidth {
+		w = dstWidth
+	}
+	if h > dstHeight {
+		h = dstHeight
+	}
+	return letterboxGeometry{
+		scale:   scale,
+		width:   w,
+		height:  h,
+		offsetX: (dstWidth - w) / 2,
+		offsetY: (dstHeight - h) / 2,
+	}
+}
+
+// Letterbox fits the image into the size with keeping the aspect ratio, and
+// fills margins with opaque black. The result is a map which has "image",
+// "scale", "offset_x" and "offset_y". "image" is the letterboxed image as
+// RawData map structure in the same format and color mode as the original,
+// "scale" is the scale factor applied to the original image, and "offset_x"
+// and "offset_y" are the position of the scaled image in the letterboxed
+// image.
+//
+// A point (x, y) detected on the letterboxed image is mapped back to the
+// original image by ((x - offset_x) / scale, (y - offset_y) / scale).
+//
+// img: target image as RawData map structure.
+//
+// width: width of the letterboxed image.
+//
+// height: height of the letterboxed image.
+//
+// interpolation: [optional] see Resize.
+func Letterbox(img data.Map, width, height int, interpolation ...string) (
+	data.Map, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("size must be greater than 0: %vx%v", width,
+			height)
+	}
+	inter, err := toInterpolation(interpolation)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ConvertMapToRawData(img)
+	if err != nil {
+		return nil, err
+	}
+	if raw.Width <= 0 || raw.Height <= 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+
+	mat, err := raw.toMat()
+	if err != nil {
+		return nil, err
+	}
+	defer mat.Delete()
+	g := newLetterboxGeometry(mat.Cols(), mat.Rows(), width, height)
+	resized := mat.Resize(g.width, g.height, inter)
+	defer resized.Delete()
+	boxed := resized.CopyMakeBorder(g.offsetY, height-g.height-g.offsetY,
+		g.offsetX, width-g.width-g.offsetX, borderValue(raw.ColorMode()))
+	defer boxed.Delete()
+	if boxed.Empty() {
+		return nil, fmt.Errorf("cannot letterbox the image into %vx%v", width,
+			height)
+	}
+
+	ret, err := raw.fromMat(boxed)
+	if err != nil {
+		return nil, err
+	}
+	return data.Map{
+		"image":    ret.ConvertToDataMap(),
+		"scale":    data.Float(g.scale),
+		"offset_x": data.Int(g.offsetX),
+		"offset_y": data.Int(g.offsetY),
+	}, nil
+}
+
+// frameResizer resizes captured frames before they are converted to tuples.
+type frameResizer struct {
+	width  int
+	height int
+	scale  float64
+}
+
+// newFrameResizer returns frameResizer from "resize_width", "resize_height"
+// and "scale" parameters. When none of them is set, returns nil. "scale"
+// cannot be set with "resize_width" or "resize_height".
+func newFrameResizer(params data.Map) (*frameResizer, error) {
+	r := &frameResizer{}
+	set := false
+	if w, err := params.Get(resizeWidthPath); err == nil {
+		width, err := data.AsInt(w)
+		if err != nil {
+			return nil, err
+		}
+		if width <= 0 {
+			return nil, fmt.Errorf("resize_width must be greater than 0: %v",
+				width)
+		}
+		r.width = int(width)
+		set = true
+	}
+
+	if h, err := params.Get(resizeHeightPath); err == nil {
+		height, err := data.AsInt(h)
+		if err != nil {
+			return nil, err
+		}
+		if height <= 0 {
+			return nil, fmt.Errorf("resize_height must be greater than 0: %v",
+				height)
+		}
+		r.height = int(height)
+		set = true
+	}
+
+	if s, err := params.Get(scalePath); err == nil {
+		if set {
+			return nil, fmt.Errorf(
+				"scale cannot be set with resize_width or resize_height")
+		}
+		if r.scale, err = data.ToFloat(s); err != nil {
+			return nil, err
+		}
+		if r.scale <= 0 {
+			return nil, fmt.Errorf("scale must be greater than 0: %v", r.scale)
+		}
+		set = true
+	}
+
+	if !set {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// size returns the size of resized frames.
+func (r *frameResizer) size(srcWidth, srcHeight int) (int, int) {
+	if r.scale > 0 {
+		return scaleSize(srcWidth, srcHeight, r.scale)
+	}
+	return keepAspectSize(srcWidth, srcHeight, r.width, r.height)
+}
+
+// resize resizes the frame into dst. Pixel area relation is used for
+// shrinking, and bilinear interpolation is used for enlarging.
+func (r *frameResizer) resize(src, dst bridge.MatVec3b) {
+	srcWidth, srcHeight := src.Cols(), src.Rows()
+	width, height := r.size(srcWidth, srcHeight)
+	inter := bridge.InterLinear
+	if width*height < srcWidth*srcHeight {
+		inter = bridge.InterArea
+	}
+	src.ResizeTo(dst, width, height, inter)
+}
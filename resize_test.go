@@ -0,0 +1,245 @@
+package opencv
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"testing"
+)
+
+func TestToInterpolation(t *testing.T) {
+	Convey("Given interpolation names", t, func() {
+		Convey("When the name is not given", func() {
+			inter, err := toInterpolation(nil)
+			Convey("Then bilinear interpolation should be returned", func() {
+				So(err, ShouldBeNil)
+				So(inter, ShouldEqual, bridge.InterLinear)
+			})
+		})
+		Convey("When the name is area", func() {
+			inter, err := toInterpolation([]string{"area"})
+			Convey("Then area interpolation should be returned", func() {
+				So(err, ShouldBeNil)
+				So(inter, ShouldEqual, bridge.InterArea)
+			})
+		})
+		Convey("When the name is not supported", func() {
+			_, err := toInterpolation([]string{"bicubic"})
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestNewLetterboxGeometry(t *testing.T) {
+	Convey("Given a 640x360 image", t, func() {
+		Convey("When fit it into 416x416", func() {
+			g := newLetterboxGeometry(640, 360, 416, 416)
+			Convey("Then the image should be scaled by the width", func() {
+				So(g, ShouldResemble, letterboxGeometry{
+					scale:   0.65,
+					width:   416,
+					height:  234,
+					offsetX: 0,
+					offsetY: 91,
+				})
+			})
+		})
+		Convey("When fit it into 100x300", func() {
+			g := newLetterboxGeometry(640, 360, 100, 300)
+			Convey("Then the image should be centered vertically", func() {
+				So(g.width, ShouldEqual, 100)
+				So(g.height, ShouldEqual, 56)
+				So(g.offsetX, ShouldEqual, 0)
+				So(g.offsetY, ShouldEqual, 122)
+			})
+		})
+	})
+}
+
+func TestResize(t *testing.T) {
+	Convey("Given a 4x2 cvmat image", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Width:  4,
+			Height: 2,
+			Data:   make([]byte, 4*2*3),
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When resize it with only width", func() {
+			resized, err := Resize(img, 2, 0, "area")
+			So(err, ShouldBeNil)
+			Convey("Then height should be decided by the aspect ratio", func() {
+				r, err := ConvertMapToRawData(resized)
+				So(err, ShouldBeNil)
+				So(r.Format, ShouldEqual, TypeCVMAT)
+				So(r.Width, ShouldEqual, 2)
+				So(r.Height, ShouldEqual, 1)
+			})
+		})
+		Convey("When scale it by 2", func() {
+			scaled, err := Scale(img, 2)
+			So(err, ShouldBeNil)
+			Convey("Then the size should be doubled", func() {
+				r, err := ConvertMapToRawData(scaled)
+				So(err, ShouldBeNil)
+				So(r.Width, ShouldEqual, 8)
+				So(r.Height, ShouldEqual, 4)
+			})
+		})
+		Convey("When letterbox it into 4x4", func() {
+			boxed, err := Letterbox(img, 4, 4)
+			So(err, ShouldBeNil)
+			Convey("Then the image should be padded vertically", func() {
+				So(boxed["scale"], ShouldEqual, data.Float(1))
+				So(boxed["offset_x"], ShouldEqual, data.Int(0))
+				So(boxed["offset_y"], ShouldEqual, data.Int(1))
+				m, err := data.AsMap(boxed["image"])
+				So(err, ShouldBeNil)
+				r, err := ConvertMapToRawData(m)
+				So(err, ShouldBeNil)
+				So(r.Width, ShouldEqual, 4)
+				So(r.Height, ShouldEqual, 4)
+			})
+		})
+		Convey("When resize it with invalid parameters", func() {
+			_, err := Resize(img, 0, 0)
+			So(err, ShouldNotBeNil)
+			_, err = Scale(img, 0)
+			So(err, ShouldNotBeNil)
+			_, err = Letterbox(img, -1, 4)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Given a 2x1 cvmat4b image which has alpha values", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT4b,
+			Width:  2,
+			Height: 1,
+			Data:   []byte{1, 2, 3, 10, 4, 5, 6, 20},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When resize it", func() {
+			resized, err := Resize(img, 4, 1, "nearest")
+			So(err, ShouldBeNil)
+			Convey("Then alpha values should be kept", func() {
+				r, err := ConvertMapToRawData(resized)
+				So(err, ShouldBeNil)
+				So(r.Format, ShouldEqual, TypeCVMAT4b)
+				So(r.Data, ShouldResemble, []byte{
+					1, 2, 3, 10, 1, 2, 3, 10, 4, 5, 6, 20, 4, 5, 6, 20})
+			})
+		})
+		Convey("When letterbox it into 2x2", func() {
+			boxed, err := Letterbox(img, 2, 2, "nearest")
+			So(err, ShouldBeNil)
+			Convey("Then alpha values should be kept and margins should be opaque", func() {
+				m, err := data.AsMap(boxed["image"])
+				So(err, ShouldBeNil)
+				r, err := ConvertMapToRawData(m)
+				So(err, ShouldBeNil)
+				So(r.Format, ShouldEqual, TypeCVMAT4b)
+				So(r.Data, ShouldResemble, []byte{
+					1, 2, 3, 10, 4, 5, 6, 20, 0, 0, 0, 255, 0, 0, 0, 255})
+			})
+		})
+	})
+
+	Convey("Given a 1x1 cvmat image in RGB mode", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Mode:   ModeRGB,
+			Width:  1,
+			Height: 1,
+			Data:   []byte{10, 20, 30},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When resize it", func() {
+			resized, err := Resize(img, 2, 0, "nearest")
+			So(err, ShouldBeNil)
+			Convey("Then the mode and the order of channels should be kept", func() {
+				r, err := ConvertMapToRawData(resized)
+				So(err, ShouldBeNil)
+				So(r.Mode, ShouldEqual, ModeRGB)
+				So(r.Width, ShouldEqual, 2)
+				So(r.Height, ShouldEqual, 2)
+				So(r.Data, ShouldResemble, []byte{
+					10, 20, 30, 10, 20, 30, 10, 20, 30, 10, 20, 30})
+			})
+		})
+	})
+
+	Convey("Given a 1x1 cvmat image in YCrCb mode", t, func() {
+		raw := RawData{
+			Format: TypeCVMAT,
+			Mode:   ModeYCrCb,
+			Width:  1,
+			Height: 1,
+			Data:   []byte{200, 100, 150},
+		}
+		img := raw.ConvertToDataMap()
+		Convey("When letterbox it into 1x2", func() {
+			boxed, err := Letterbox(img, 1, 2)
+			So(err, ShouldBeNil)
+			Convey("Then the margin should be black in YCrCb", func() {
+				m, err := data.AsMap(boxed["image"])
+				So(err, ShouldBeNil)
+				r, err := ConvertMapToRawData(m)
+				So(err, ShouldBeNil)
+				So(r.Mode, ShouldEqual, ModeYCrCb)
+				So(r.Data, ShouldResemble, []byte{200, 100, 150, 0, 128, 128})
+			})
+		})
+	})
+}
+
+func TestFrameResizer(t *testing.T) {
+	Convey("Given frame resizer parameters", t, func() {
+		Convey("When only resize_width is set", func() {
+			r, err := newFrameResizer(data.Map{"resize_width": data.Int(640)})
+			So(err, ShouldBeNil)
+			Convey("Then height should be decided by the aspect ratio", func() {
+				w, h := r.size(1920, 1080)
+				So(w, ShouldEqual, 640)
+				So(h, ShouldEqual, 360)
+			})
+			Convey("Then height should be at least 1 with an extreme aspect ratio", func() {
+				w, h := r.size(1280*1000, 480)
+				So(w, ShouldEqual, 640)
+				So(h, ShouldEqual, 1)
+			})
+		})
+		Convey("When scale is set", func() {
+			r, err := newFrameResizer(data.Map{"scale": data.Float(0.25)})
+			So(err, ShouldBeNil)
+			Convey("Then the size should be scaled", func() {
+				w, h := r.size(1920, 1080)
+				So(w, ShouldEqual, 480)
+				So(h, ShouldEqual, 270)
+			})
+		})
+		Convey("When no parameter is set", func() {
+			r, err := newFrameResizer(data.Map{})
+			Convey("Then resizer should not be created", func() {
+				So(err, ShouldBeNil)
+				So(r, ShouldBeNil)
+			})
+		})
+		Convey("When invalid parameters are set", func() {
+			testMap := data.Map{
+				"resize_width":  data.Int(0),
+				"resize_height": data.String("a"),
+				"scale":         data.Float(-1),
+			}
+			for k, v := range testMap {
+				v := v
+				Convey("Then an error should be occurred with "+k, func() {
+					_, err := newFrameResizer(data.Map{k: v})
+					So(err, ShouldNotBeNil)
+				})
+			}
+		})
+	})
+}
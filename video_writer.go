@@ -0,0 +1,360 @@
+package opencv
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/bql"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"os"
+	"sync"
+	"time"
+)
+
+// VideoWriterCreator is a creator of a video writer sink.
+type VideoWriterCreator struct{}
+
+var (
+	codecPath          = data.MustCompilePath("codec")
+	rotateIntervalPath = data.MustCompilePath("rotate_interval")
+	rotateFramesPath   = data.MustCompilePath("rotate_frames")
+	maxFilesPath       = data.MustCompilePath("max_files")
+)
+
+// CreateSink creates a video writer sink using OpenCV video writer
+// (`VideoWriter::open`). The video file is opened when the first frame is
+// written, and the frame size of the file is decided by the first frame.
+//
+// WITH parameters.
+//
+// file: [required] An output file path (e.g. /data/output.avi). The path can
+// be a strftime-style template (e.g. /data/camera1_%Y%m%d_%H%M%S.avi), which
+// is formatted with the timestamp of the first tuple of the file. Supported
+// directives are %Y, %y, %m, %d, %j, %H, %M, %S, %f (microseconds) and %%.
+//
+// fps: Frame per second of the output video, default is 30.
+//
+// codec: FourCC of the video codec (e.g. "XVID"), default is "MJPG".
+//
+// rotate_interval: The length of a video file in seconds. When the tuple
+// timestamp passes the interval from the first frame of the current file,
+// the sink starts a new file. If set empty or "0" then will be ignored.
+//
+// rotate_frames: The number of frames of a video file. When the current file
+// has the number of frames, the sink starts a new file. If set empty or "0"
+// then will be ignored.
+//
+// max_files: The number of video files to be retained. When the sink starts
+// a new file, the oldest files which are created by the sink are removed over
+// the number. If set empty or "0" then all files are retained. Only files
+// created after the sink is created are counted, so files created before
+// restarting the sink (or SensorBee) are not removed.
+//
+// When "rotate_interval" or "rotate_frames" is set, "file" is required to have
+// directives other than %% to avoid overwriting the previous file. When the
+// formatted name is still the same as the current file (e.g. a daily template
+// rotated by frames), the sink continues writing to the current file until
+// the name changes.
+func (c *VideoWriterCreator) CreateSink(ctx *core.Context,
+	ioParams *bql.IOParams, params data.Map) (core.Sink, error) {
+
+	fp, err := params.Get(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("video writer needs file path")
+	}
+	file, err := data.AsString(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	fps := 30.0
+	if f, err := params.Get(fpsPath); err == nil {
+		if fps, err = data.ToFloat(f); err != nil {
+			return nil, err
+		}
+		if fps <= 0 {
+			return nil, fmt.Errorf("fps must be positive: %v", fps)
+		}
+	}
+
+	fourcc := bridge.FourCCMJPG
+	if cd, err := params.Get(codecPath); err == nil {
+		codec, err := data.AsString(cd)
+		if err != nil {
+			return nil, err
+		}
+		if fourcc, err = toFourCC(codec); err != nil {
+			return nil, err
+		}
+	}
+
+	ri, err := params.Get(rotateIntervalPath)
+	if err != nil {
+		ri = data.Float(0) // will be ignored
+	}
+	rotateInterval, err := data.ToFloat(ri)
+	if err != nil {
+		return nil, err
+	}
+	if rotateInterval < 0 {
+		return nil, fmt.Errorf("rotate_interval must not be negative: %v",
+			rotateInterval)
+	}
+
+	rf, err := params.Get(rotateFramesPath)
+	if err != nil {
+		rf = data.Int(0) // will be ignored
+	}
+	rotateFrames, err := data.AsInt(rf)
+	if err != nil {
+		return nil, err
+	}
+	if rotateFrames < 0 {
+		return nil, fmt.Errorf("rotate_frames must not be negative: %v",
+			rotateFrames)
+	}
+
+	mf, err := params.Get(maxFilesPath)
+	if err != nil {
+		mf = data.Int(0) // will be ignored
+	}
+	maxFiles, err := data.AsInt(mf)
+	if err != nil {
+		return nil, err
+	}
+	if maxFiles < 0 {
+		return nil, fmt.Errorf("max_files must not be negative: %v", maxFiles)
+	}
+
+	if _, err := strftime(file, time.Time{}); err != nil {
+		return nil, err
+	}
+	if (rotateInterval > 0 || rotateFrames > 0) && !hasTimeDirective(file) {
+		return nil, fmt.Errorf("file must be a template to rotate video files: %v",
+			file)
+	}
+
+	return &videoWriterSink{
+		file:           file,
+		fps:            fps,
+		fourcc:         fourcc,
+		rotateInterval: time.Duration(rotateInterval * float64(time.Second)),
+		rotateFrames:   rotateFrames,
+		maxFiles:       int(maxFiles),
+	}, nil
+}
+
+func toFourCC(codec string) (int, error) {
+	if len(codec) != 4 {
+		return 0, fmt.Errorf("codec must be four characters: '%v'", codec)
+	}
+	return bridge.FourCC(codec[0], codec[1], codec[2], codec[3]), nil
+}
+
+type videoWriterSink struct {
+	mu             sync.Mutex
+	file           string
+	fps            float64
+	fourcc         int
+	rotateInterval time.Duration
+	rotateFrames   int64
+	maxFiles       int
+
+	writer   bridge.VideoWriter
+	opened   bool
+	closed   bool
+	width    int
+	height   int
+	name     string
+	start    time.Time
+	frames   int64
+	segments []string
+}
+
+// Write writes a frame to the video file. The tuple is required to be
+// structured as RawData. When the current file reaches the rotation limit, the
+// file is closed and a new file is opened before writing the frame.
+func (s *videoWriterSink) Write(ctx *core.Context, t *core.Tuple) error {
+	raw, err := ConvertMapToRawData(t.Data)
+	if err != nil {
+		return err
+	}
+	mat, err := raw.ToMatVec3b()
+	if err != nil {
+		return err
+	}
+	defer mat.Delete()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("video writer has already been closed: %v", s.file)
+	}
+	if s.opened {
+		rotate, err := s.rotate(ctx, t.Timestamp)
+		if err != nil {
+			return err
+		}
+		if rotate {
+			s.writer.Delete()
+			s.opened = false
+		}
+	}
+	if !s.opened {
+		if err := s.open(ctx, t.Timestamp, raw.Width, raw.Height); err != nil {
+			return err
+		}
+	} else if raw.Width != s.width || raw.Height != s.height {
+		return fmt.Errorf("frame size %dx%d is different from the video size %dx%d",
+			raw.Width, raw.Height, s.width, s.height)
+	}
+	s.writer.Write(mat)
+	s.frames++
+	return nil
+}
+
+func (s *videoWriterSink) needsRotation(ts time.Time) bool {
+	if s.rotateFrames > 0 && s.frames >= s.rotateFrames {
+		return true
+	}
+	return s.rotateInterval > 0 && ts.Sub(s.start) >= s.rotateInterval
+}
+
+// rotate returns whether the current file should be closed before writing the
+// frame of the timestamp. When the current file reaches the rotation limit
+// but the formatted name is not changed, the limit is reset and the sink
+// continues writing to the current file.
+func (s *videoWriterSink) rotate(ctx *core.Context, ts time.Time) (bool, error) {
+	if !s.needsRotation(ts) {
+		return false, nil
+	}
+	name, err := strftime(s.file, ts)
+	if err != nil {
+		return false, err
+	}
+	if name != s.name {
+		return true, nil
+	}
+	ctx.Log().Warnf("video file name is not changed, continue writing: %v",
+		name)
+	s.start = ts
+	s.frames = 0
+	return false, nil
+}
+
+func (s *videoWriterSink) open(ctx *core.Context, ts time.Time, width,
+	height int) error {
+	name, err := strftime(s.file, ts)
+	if err != nil {
+		return err
+	}
+	s.writer = bridge.NewVideoWriter()
+	s.writer.OpenWithFourCC(name, s.fourcc, s.fps, width, height)
+	if !s.writer.IsOpened() {
+		s.writer.Delete()
+		return fmt.Errorf("error opening video file: %v", name)
+	}
+	ctx.Log().Infof("start writing video file: %v", name)
+	s.opened = true
+	s.width = width
+	s.height = height
+	s.name = name
+	s.start = ts
+	s.frames = 0
+	s.addSegment(ctx, name)
+	return nil
+}
+
+// addSegment records the file as the newest one, and removes the oldest files
+// over max_files. A reopened name (e.g. "%H" of the next day) is moved to the
+// newest so that the current file is never removed.
+func (s *videoWriterSink) addSegment(ctx *core.Context, name string) {
+	for i, n := range s.segments {
+		if n == name {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+	s.segments = append(s.segments, name)
+	if s.maxFiles <= 0 {
+		return
+	}
+	for len(s.segments) > s.maxFiles {
+		old := s.segments[0]
+		s.segments = s.segments[1:]
+		if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+			ctx.Log().Warnf("cannot remove the old video file: %v", err)
+		}
+	}
+}
+
+// Close releases the video writer, the video file is completed.
+func (s *videoWriterSink) Close(ctx *core.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.opened {
+		s.writer.Delete()
+		s.opened = false
+	}
+	return nil
+}
+
+// hasTimeDirective returns whether the strftime-style layout has a directive
+// which is formatted with the timestamp, i.e. other than %%.
+func hasTimeDirective(layout string) bool {
+	for i := 0; i+1 < len(layout); i++ {
+		if layout[i] != '%' {
+			continue
+		}
+		if layout[i+1] != '%' {
+			return true
+		}
+		i++
+	}
+	return false
+}
+
+// strftime formats the timestamp with the strftime-style layout.
+func strftime(layout string, t time.Time) (string, error) {
+	var b bytes.Buffer
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			b.WriteByte(layout[i])
+			continue
+		}
+		i++
+		if i >= len(layout) {
+			return "", fmt.Errorf("'%v' ends with an incomplete directive", layout)
+		}
+		switch layout[i] {
+		case 'Y':
+			fmt.Fprintf(&b, "%04d", t.Year())
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 'f':
+			fmt.Fprintf(&b, "%06d", t.Nanosecond()/1000)
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("'%%%c' directive is not supported", layout[i])
+		}
+	}
+	return b.String(), nil
+}
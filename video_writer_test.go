@@ -0,0 +1,273 @@
+package opencv
+
+import (
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/sensorbee/opencv.v0/bridge"
+	"gopkg.in/sensorbee/sensorbee.v0/bql"
+	"gopkg.in/sensorbee/sensorbee.v0/core"
+	"gopkg.in/sensorbee/sensorbee.v0/data"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetVideoWriterCreator(t *testing.T) {
+	ctx := &core.Context{}
+	ioParams := &bql.IOParams{}
+	Convey("Given a VideoWriter creator", t, func() {
+		sc := VideoWriterCreator{}
+		Convey("When create sink with full parameters", func() {
+			params := data.Map{
+				"file":  data.String("/data/output.avi"),
+				"fps":   data.Float(15),
+				"codec": data.String("XVID"),
+			}
+			Convey("Then creator should initialize video writer sink", func() {
+				s, err := sc.CreateSink(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				sink, ok := s.(*videoWriterSink)
+				So(ok, ShouldBeTrue)
+				So(sink.file, ShouldEqual, "/data/output.avi")
+				So(sink.fps, ShouldEqual, 15)
+				So(sink.fourcc, ShouldEqual, bridge.FourCC('X', 'V', 'I', 'D'))
+			})
+		})
+
+		Convey("When create sink with only file", func() {
+			params := data.Map{
+				"file": data.String("/data/output.avi"),
+			}
+			Convey("Then sink should set default values", func() {
+				s, err := sc.CreateSink(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				sink, ok := s.(*videoWriterSink)
+				So(ok, ShouldBeTrue)
+				So(sink.fps, ShouldEqual, 30)
+				So(sink.fourcc, ShouldEqual, bridge.FourCCMJPG)
+				So(sink.opened, ShouldBeFalse)
+			})
+		})
+
+		Convey("When create sink with rotation parameters", func() {
+			params := data.Map{
+				"file":            data.String("/data/camera1_%Y%m%d_%H%M%S.avi"),
+				"rotate_interval": data.Float(60),
+				"rotate_frames":   data.Int(1000),
+				"max_files":       data.Int(10),
+			}
+			Convey("Then sink should set rotation values", func() {
+				s, err := sc.CreateSink(ctx, ioParams, params)
+				So(err, ShouldBeNil)
+				sink, ok := s.(*videoWriterSink)
+				So(ok, ShouldBeTrue)
+				So(sink.rotateInterval, ShouldEqual, time.Minute)
+				So(sink.rotateFrames, ShouldEqual, 1000)
+				So(sink.maxFiles, ShouldEqual, 10)
+			})
+		})
+
+		Convey("When create sink with rotation and not template file", func() {
+			params := data.Map{
+				"file":          data.String("/data/output.avi"),
+				"rotate_frames": data.Int(1000),
+			}
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.CreateSink(ctx, ioParams, params)
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When create sink with rotation and a template which has only %%", func() {
+			params := data.Map{
+				"file":            data.String("/data/output_%%.avi"),
+				"rotate_interval": data.Float(60),
+			}
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.CreateSink(ctx, ioParams, params)
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When create sink without file", func() {
+			params := data.Map{
+				"fps": data.Int(10),
+			}
+			Convey("Then creator should occur an error", func() {
+				s, err := sc.CreateSink(ctx, ioParams, params)
+				So(err, ShouldNotBeNil)
+				So(s, ShouldBeNil)
+			})
+		})
+
+		Convey("When create sink with invalid option parameters", func() {
+			params := data.Map{
+				"file": data.String("/data/output.avi"),
+			}
+			testMap := data.Map{
+				"fps":             data.String("@"),
+				"codec":           data.String("MPEG4"),
+				"rotate_interval": data.String("a"),
+				"rotate_frames":   data.String("b"),
+				"max_files":       data.String("c"),
+			}
+			negativeMap := data.Map{
+				"rotate_interval": data.Float(-1),
+				"rotate_frames":   data.Int(-1),
+				"max_files":       data.Int(-1),
+			}
+			for k, v := range negativeMap {
+				v := v
+				Convey("Then creator should occur an error with negative "+k,
+					func() {
+						params["file"] = data.String("/data/output_%H.avi")
+						params[k] = v
+						s, err := sc.CreateSink(ctx, ioParams, params)
+						So(err, ShouldNotBeNil)
+						So(s, ShouldBeNil)
+					})
+			}
+			for k, v := range testMap {
+				v := v
+				msg := fmt.Sprintf("with %v error", k)
+				Convey("Then creator should occur a parse error on option parameters "+msg,
+					func() {
+						params[k] = v
+						s, err := sc.CreateSink(ctx, ioParams, params)
+						So(err, ShouldNotBeNil)
+						So(s, ShouldBeNil)
+					})
+			}
+		})
+	})
+}
+
+func TestVideoWriterRotation(t *testing.T) {
+	Convey("Given a rotating video writer sink", t, func() {
+		now := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+		s := &videoWriterSink{
+			rotateInterval: 10 * time.Second,
+			rotateFrames:   100,
+			start:          now,
+		}
+		Convey("When the current file does not reach limits", func() {
+			s.frames = 99
+			Convey("Then the sink should not rotate", func() {
+				So(s.needsRotation(now.Add(9*time.Second)), ShouldBeFalse)
+			})
+		})
+		Convey("When the current file reaches the frame limit", func() {
+			s.frames = 100
+			Convey("Then the sink should rotate", func() {
+				So(s.needsRotation(now), ShouldBeTrue)
+			})
+		})
+		Convey("When the tuple timestamp passes the interval", func() {
+			Convey("Then the sink should rotate", func() {
+				So(s.needsRotation(now.Add(10*time.Second)), ShouldBeTrue)
+			})
+		})
+		Convey("When the current file reaches the limit but the name is not changed", func() {
+			ctx := core.NewContext(&core.ContextConfig{})
+			s.file = "/data/camera1_%Y%m%d.avi"
+			s.name = "/data/camera1_20160102.avi"
+			s.frames = 100
+			ts := now.Add(time.Second)
+			rotate, err := s.rotate(ctx, ts)
+			So(err, ShouldBeNil)
+			Convey("Then the sink should continue writing with reset limits", func() {
+				So(rotate, ShouldBeFalse)
+				So(s.frames, ShouldEqual, 0)
+				So(s.start, ShouldResemble, ts)
+				So(s.needsRotation(ts), ShouldBeFalse)
+			})
+		})
+	})
+}
+
+func TestVideoWriterRetention(t *testing.T) {
+	ctx := core.NewContext(&core.ContextConfig{})
+	Convey("Given a video writer sink which retains 2 files", t, func() {
+		dir, err := ioutil.TempDir("", "video_writer")
+		So(err, ShouldBeNil)
+		Reset(func() {
+			os.RemoveAll(dir)
+		})
+		names := map[string]string{}
+		for _, n := range []string{"a", "b", "c"} {
+			names[n] = filepath.Join(dir, n+".avi")
+			So(ioutil.WriteFile(names[n], []byte{}, 0644), ShouldBeNil)
+		}
+		s := &videoWriterSink{
+			maxFiles: 2,
+		}
+		s.addSegment(ctx, names["a"])
+		s.addSegment(ctx, names["b"])
+		Convey("When the sink reopens the oldest name", func() {
+			s.addSegment(ctx, names["a"])
+			Convey("Then the reopened file should not be removed", func() {
+				So(s.segments, ShouldResemble, []string{names["b"], names["a"]})
+				_, err := os.Stat(names["a"])
+				So(err, ShouldBeNil)
+				_, err = os.Stat(names["b"])
+				So(err, ShouldBeNil)
+			})
+			Convey("And opens a new file", func() {
+				s.addSegment(ctx, names["c"])
+				Convey("Then the oldest file should be removed", func() {
+					So(s.segments, ShouldResemble, []string{names["a"], names["c"]})
+					_, err := os.Stat(names["b"])
+					So(os.IsNotExist(err), ShouldBeTrue)
+				})
+			})
+		})
+	})
+}
+
+func TestHasTimeDirective(t *testing.T) {
+	Convey("Given file templates", t, func() {
+		testMap := map[string]bool{
+			"output.avi":         false,
+			"output_%%.avi":      false,
+			"output_%%H.avi":     false,
+			"output_%H.avi":      true,
+			"output_%%_%Y.avi":   true,
+			"output_100%%%d.avi": true,
+		}
+		for k, v := range testMap {
+			k, v := k, v
+			Convey("Then "+k+" should be checked", func() {
+				So(hasTimeDirective(k), ShouldEqual, v)
+			})
+		}
+	})
+}
+
+func TestStrftime(t *testing.T) {
+	Convey("Given a timestamp", t, func() {
+		ts := time.Date(2016, 3, 4, 5, 6, 7, 8000, time.UTC)
+		Convey("When format with supported directives", func() {
+			name, err := strftime("cam_%Y%m%d_%H%M%S.%f_%y_%j_%%.avi", ts)
+			Convey("Then the name should be formatted", func() {
+				So(err, ShouldBeNil)
+				So(name, ShouldEqual, "cam_20160304_050607.000008_16_064_%.avi")
+			})
+		})
+		Convey("When format with a not supported directive", func() {
+			_, err := strftime("cam_%Q.avi", ts)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+		Convey("When format with an incomplete directive", func() {
+			_, err := strftime("cam_%", ts)
+			Convey("Then an error should be occurred", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}